@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
 
 	"github.com/pojol/braid-go/components"
 	"github.com/pojol/braid-go/components/depends/blog"
 	"github.com/pojol/braid-go/module"
 	"github.com/pojol/braid-go/module/meta"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -28,54 +33,139 @@ const (
 
 var (
 	ErrTypeConvFailed = errors.New("type conversion failed")
+
+	// ErrNoDefaultService 还没有创建 braid 服务时调用了包级别的函数
+	ErrNoDefaultService = errors.New("braid default service not created")
 )
 
 // Braid framework instance
 type Braid struct {
 	info meta.ServiceInfo
+	parm Parm
 
 	log *blog.Logger
 
 	director components.IDirector
+
+	// 事件回调使用的订阅（OnNodeAdded, OnLeaderChanged ...
+	events   []module.IChannel
+	eventSeq int
+
+	sync.Mutex
 }
 
 var (
-	braidGlobal *Braid
+	// 包级别函数（Topic, Send, Logger）使用的默认服务
+	braidGlobal   *Braid
+	braidGlobalMu sync.RWMutex
 )
 
+// Default 获取默认的 braid 服务（进程内第一个被创建的服务，关闭后由下一个创建的服务接替
+func Default() *Braid {
+	braidGlobalMu.RLock()
+	defer braidGlobalMu.RUnlock()
+
+	return braidGlobal
+}
+
+// SetDefault 设置包级别函数（Topic, Send, Logger）使用的默认服务
+func SetDefault(b *Braid) {
+	braidGlobalMu.Lock()
+	defer braidGlobalMu.Unlock()
+
+	braidGlobal = b
+}
+
 // NewService - 创建一个新的 braid 服务
 //
 //	name 服务名称
 //	id   服务id （唯一标识
 //	director 服务组件构建器
-func NewService(name string, id string, director components.IDirector) (*Braid, error) {
+//	opts     braid 配置
+func NewService(name string, id string, director components.IDirector, opts ...Option) (*Braid, error) {
+
+	p := DefaultParm
+	for _, opt := range opts {
+		opt(&p)
+	}
 
 	director.SetServiceInfo(meta.ServiceInfo{ID: id, Name: name})
-	director.Build()
+	err := director.Build()
+	if err != nil {
+		return nil, fmt.Errorf("braid build err : %w", err)
+	}
 
-	braidGlobal = &Braid{
+	b := &Braid{
 		info:     meta.ServiceInfo{Name: name, ID: id},
+		parm:     p,
 		log:      director.Logger(),
 		director: director,
 	}
 
-	return braidGlobal, nil
+	braidGlobalMu.Lock()
+	if braidGlobal == nil {
+		braidGlobal = b
+	}
+	braidGlobalMu.Unlock()
+
+	return b, nil
 }
 
-// Init braid init
-func (b *Braid) Init() error {
-	return b.director.Init()
+// Init 按照依赖顺序初始化 braid 的模块，失败时会关闭已经初始化的模块
+func (b *Braid) Init(ctx context.Context) error {
+	return b.director.Init(ctx)
 }
 
 // Run 运行braid
-func (b *Braid) Run() {
+//
+//	使用 WithWaitSignal 时会阻塞，直到收到退出信号或 ctx 结束；之后依次
+//	注销服务发现，停止接收新的 rpc 请求并等待处理中的请求，释放选举锁，释放链路缓存中的 token，关闭 pubsub 的订阅
+func (b *Braid) Run(ctx context.Context) error {
 	fmt.Printf(banner, Version)
-	b.director.Run()
+
+	if !b.parm.WaitSignal {
+		return b.director.Run(ctx)
+	}
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, b.parm.Signals...)
+	defer signal.Stop(sigch)
+
+	err := b.director.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case sig := <-sigch:
+		b.log.Infof("[braid] receive signal %v, shutdown", sig)
+	case <-ctx.Done():
+		b.log.Infof("[braid] context done %v, shutdown", ctx.Err())
+	}
+
+	cctx := context.Background()
+	if b.parm.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		cctx, cancel = context.WithTimeout(cctx, b.parm.ShutdownTimeout)
+		defer cancel()
+	}
+
+	return b.Close(cctx)
+}
+
+// Health 获取 braid 所有模块的健康状态（liveness / readiness
+func (b *Braid) Health(ctx context.Context) components.HealthReport {
+	return b.director.Health(ctx)
+}
+
+// HealthHandler 获取提供给 k8s 探针使用的 http handler（/healthz, /readyz
+func (b *Braid) HealthHandler() http.Handler {
+	return components.NewHealthHandler(b.director.Health, components.DefaultHealthTimeout)
 }
 
 // Topic 获取或创建一个pubsub消息主题
-func Topic(name string) module.ITopic {
-	return braidGlobal.director.Pubsub().GetTopic(name)
+func (b *Braid) Topic(name string) module.ITopic {
+	return b.director.Pubsub().GetTopic(name)
 }
 
 // Send 发送rpc请求
@@ -86,15 +176,114 @@ func Topic(name string) module.ITopic {
 //	args   请求参数
 //	reply  返回参数
 //	opts   rpc调用选项
+func (b *Braid) Send(ctx context.Context, target, methon, token string,
+	args, reply interface{},
+	opts ...interface{}) error {
+	return b.director.Client().Invoke(ctx, target, methon, token, args, reply, opts...)
+}
+
+// NewStream 创建一个流式rpc调用
+//
+//	target 目标服务名称
+//	methon 目标服务方法
+//	token  用户的唯一标识id
+//	desc   流的描述（通常来自生成代码中的 ServiceDesc.Streams
+//	opts   rpc调用选项
+func (b *Braid) NewStream(ctx context.Context, target, methon, token string,
+	desc *grpc.StreamDesc,
+	opts ...interface{}) (grpc.ClientStream, error) {
+	return b.director.Client().NewStream(ctx, target, methon, token, desc, opts...)
+}
+
+// Broadcast 并发调用 target 服务的所有节点
+//
+//	target   目标服务名称
+//	methon   目标服务方法
+//	args     请求参数（所有节点共享
+//	newReply 为每个节点创建返回参数
+//	opts     rpc调用选项（grpcclient.WithCallTimeout, WithCallQuorum
+func (b *Braid) Broadcast(ctx context.Context, target, methon string,
+	args interface{}, newReply func() interface{},
+	opts ...interface{}) ([]module.InvokeResult, error) {
+	return b.director.Client().InvokeAll(ctx, target, methon, args, newReply, opts...)
+}
+
+// Logger 获取服务的日志
+func (b *Braid) Logger() *blog.Logger {
+	return b.log
+}
+
+// Info 获取服务信息
+func (b *Braid) Info() meta.ServiceInfo {
+	return b.info
+}
+
+// Topic 通过默认服务获取或创建一个pubsub消息主题（见 Default
+func Topic(name string) module.ITopic {
+	b := Default()
+	if b == nil {
+		panic(ErrNoDefaultService)
+	}
+	return b.Topic(name)
+}
+
+// Send 通过默认服务发送rpc请求（见 Default
 func Send(ctx context.Context, target, methon, token string,
 	args, reply interface{},
 	opts ...interface{}) error {
-	return braidGlobal.director.Client().Invoke(ctx, target, methon, token, args, reply, opts...)
+
+	b := Default()
+	if b == nil {
+		return ErrNoDefaultService
+	}
+	return b.Send(ctx, target, methon, token, args, reply, opts...)
 }
 
-// Close 关闭braid
-func (b *Braid) Close() {
+// NewStream 通过默认服务创建一个流式rpc调用（见 Default
+func NewStream(ctx context.Context, target, methon, token string,
+	desc *grpc.StreamDesc,
+	opts ...interface{}) (grpc.ClientStream, error) {
+
+	b := Default()
+	if b == nil {
+		return nil, ErrNoDefaultService
+	}
+	return b.NewStream(ctx, target, methon, token, desc, opts...)
+}
+
+// Broadcast 通过默认服务并发调用 target 服务的所有节点（见 Default
+func Broadcast(ctx context.Context, target, methon string,
+	args interface{}, newReply func() interface{},
+	opts ...interface{}) ([]module.InvokeResult, error) {
+
+	b := Default()
+	if b == nil {
+		return nil, ErrNoDefaultService
+	}
+	return b.Broadcast(ctx, target, methon, args, newReply, opts...)
+}
+
+// Logger 获取默认服务的日志（见 Default
+func Logger() *blog.Logger {
+	b := Default()
+	if b == nil {
+		panic(ErrNoDefaultService)
+	}
+	return b.Logger()
+}
+
+// Close 关闭braid，返回的错误中包含所有模块关闭时产生的错误（components.CloseErrors
+//
+//	如果关闭的是默认服务，包级别的函数将不再可用，直到创建新的服务或调用 SetDefault
+func (b *Braid) Close(ctx context.Context) error {
+
+	braidGlobalMu.Lock()
+	if braidGlobal == b {
+		braidGlobal = nil
+	}
+	braidGlobalMu.Unlock()
 
-	b.director.Close()
+	b.closeEvents()
 
+	return b.director.Close(ctx)
 }
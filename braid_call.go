@@ -0,0 +1,110 @@
+package braid
+
+import (
+	"context"
+
+	"github.com/pojol/braid-go/components/rpcgrpc/grpcclient"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// CallOption 单次 rpc 调用的配置（grpcclient.WithCallTimeout, WithCallToken, WithCallStrategy, WithCallNode ...
+type CallOption = grpcclient.CallOption
+
+// Method 带有请求和返回类型的 rpc 方法，请求和返回的类型在编译期确定
+//
+//	var Routing = braid.NewMethod[proto.RouteReq, proto.RouteRes]("base", "/proto.listen/routing")
+//	res, err := Routing.Call(ctx, b, &proto.RouteReq{})
+type Method[Req, Resp any] struct {
+	// 目标服务名称
+	Target string
+
+	// 目标服务方法
+	Name string
+}
+
+// NewMethod 创建一个 rpc 方法
+func NewMethod[Req, Resp any](target, name string) Method[Req, Resp] {
+	return Method[Req, Resp]{Target: target, Name: name}
+}
+
+// Call 通过 braid 服务调用方法，b 为空时使用默认服务（见 Default
+func (m Method[Req, Resp]) Call(ctx context.Context, b *Braid, req *Req, opts ...CallOption) (*Resp, error) {
+	return Call[Req, Resp](ctx, b, m.Target, m.Name, req, opts...)
+}
+
+// CallAll 通过 braid 服务调用方法所在服务的所有节点（见 CallAll
+func (m Method[Req, Resp]) CallAll(ctx context.Context, b *Braid, req *Req, opts ...CallOption) ([]CallResult[Resp], error) {
+	return CallAll[Req, Resp](ctx, b, m.Target, m.Name, req, opts...)
+}
+
+// CallResult 广播调用中单个节点的结果
+type CallResult[Resp any] struct {
+	Node  meta.Node
+	Reply *Resp
+	Err   error
+}
+
+// Call 发送rpc请求，返回 reply
+//
+//	b      braid 服务，为空时使用默认服务（见 Default
+//	target 目标服务名称
+//	methon 目标服务方法
+//	req    请求参数
+//	opts   rpc调用选项，token 通过 grpcclient.WithCallToken 传入
+func Call[Req, Resp any](ctx context.Context, b *Braid, target, methon string, req *Req, opts ...CallOption) (*Resp, error) {
+
+	if b == nil {
+		b = Default()
+		if b == nil {
+			return nil, ErrNoDefaultService
+		}
+	}
+
+	callopts := make([]interface{}, 0, len(opts))
+	for _, opt := range opts {
+		callopts = append(callopts, opt)
+	}
+
+	reply := new(Resp)
+	err := b.Send(ctx, target, methon, "", req, reply, callopts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// CallAll 并发调用 target 服务的所有节点，返回每个节点的结果
+//
+//	opts 通过 grpcclient.WithCallTimeout 设置所有节点共享的超时时间，
+//	通过 grpcclient.WithCallQuorum 设置需要成功的最少节点数（默认需要所有节点成功
+//	成功的节点数没有达到 quorum 时，返回的错误为 grpcclient.ErrQuorumNotReached，同时仍然返回每个节点的结果
+func CallAll[Req, Resp any](ctx context.Context, b *Braid, target, methon string, req *Req, opts ...CallOption) ([]CallResult[Resp], error) {
+
+	if b == nil {
+		b = Default()
+		if b == nil {
+			return nil, ErrNoDefaultService
+		}
+	}
+
+	callopts := make([]interface{}, 0, len(opts))
+	for _, opt := range opts {
+		callopts = append(callopts, opt)
+	}
+
+	lst, err := b.Broadcast(ctx, target, methon, req, func() interface{} {
+		return new(Resp)
+	}, callopts...)
+
+	results := make([]CallResult[Resp], 0, len(lst))
+	for _, res := range lst {
+		results = append(results, CallResult[Resp]{
+			Node:  res.Node,
+			Reply: res.Reply.(*Resp),
+			Err:   res.Err,
+		})
+	}
+
+	return results, err
+}
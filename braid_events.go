@@ -0,0 +1,174 @@
+package braid
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// LeaderEvent 选举状态变更事件
+type LeaderEvent struct {
+	// 服务id
+	ID string
+
+	// 是否为主节点
+	Master bool
+
+	// meta.ESlave, meta.EMaster
+	State int32
+}
+
+// subscribe 为回调创建一个独立的 channel（保证每个回调都能收到所有的消息），channel 会在 Close 时关闭
+func (b *Braid) subscribe(topic string, handler module.Handler) error {
+
+	b.Lock()
+	b.eventSeq++
+	name := fmt.Sprintf("%s-%s-%d", meta.ModuleEvent, b.info.ID, b.eventSeq)
+	b.Unlock()
+
+	channel, err := b.director.Pubsub().GetTopic(topic).Sub(context.TODO(), name)
+	if err != nil {
+		return fmt.Errorf("braid subscribe %v err : %w", topic, err)
+	}
+
+	channel.Arrived(func(msg *meta.Message) error {
+		defer func() {
+			if r := recover(); r != nil {
+				b.log.Errf("[braid] event handler %v panic %v", topic, r)
+			}
+		}()
+
+		return handler(msg)
+	})
+
+	b.Lock()
+	b.events = append(b.events, channel)
+	b.Unlock()
+
+	return nil
+}
+
+// onNode 节点事件，同一个节点的重复事件（多个服务发现实例发布的）只会回调一次
+//
+//	在 Run 之后订阅时，director 中已知的节点（见 DefaultDirector.Nodes）会先作为 add 事件回放给回调
+func (b *Braid) onNode(event string, fn func(meta.Node)) error {
+
+	var mu sync.Mutex
+	nodes := make(map[string]meta.Node)
+
+	err := b.subscribe(meta.TopicDiscoverServiceUpdate, func(msg *meta.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		dmsg := meta.DecodeUpdateMsg(msg)
+
+		_, known := nodes[dmsg.Nod.ID]
+		switch dmsg.Event {
+		case meta.TopicDiscoverServiceNodeAdd:
+			nodes[dmsg.Nod.ID] = dmsg.Nod
+		case meta.TopicDiscoverServiceNodeRmv:
+			delete(nodes, dmsg.Nod.ID)
+		case meta.TopicDiscoverServiceNodeUpdate:
+			nodes[dmsg.Nod.ID] = dmsg.Nod
+		}
+
+		if dmsg.Event != event {
+			return nil
+		}
+
+		if (event == meta.TopicDiscoverServiceNodeAdd && !known) ||
+			(event != meta.TopicDiscoverServiceNodeAdd && known) {
+			fn(dmsg.Nod)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 回放订阅之前已经加入的节点（订阅之后收到的重复 add 事件会被忽略
+	lister, ok := b.director.(interface{ Nodes() []meta.Node })
+	if !ok {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, nod := range lister.Nodes() {
+		if _, known := nodes[nod.ID]; known {
+			continue
+		}
+
+		nodes[nod.ID] = nod
+		if event == meta.TopicDiscoverServiceNodeAdd {
+			fn(nod)
+		}
+	}
+
+	return nil
+}
+
+// OnNodeAdded 服务发现中有新的节点加入
+func (b *Braid) OnNodeAdded(fn func(nod meta.Node)) error {
+	return b.onNode(meta.TopicDiscoverServiceNodeAdd, fn)
+}
+
+// OnNodeRemoved 服务发现中有节点退出
+func (b *Braid) OnNodeRemoved(fn func(nod meta.Node)) error {
+	return b.onNode(meta.TopicDiscoverServiceNodeRmv, fn)
+}
+
+// OnNodeUpdated 服务发现中有节点的信息（metadata）更新
+func (b *Braid) OnNodeUpdated(fn func(nod meta.Node)) error {
+	return b.onNode(meta.TopicDiscoverServiceNodeUpdate, fn)
+}
+
+// OnLeaderChanged 当前服务的选举状态变更（成为主节点 or 从节点），只在状态发生变化时回调
+func (b *Braid) OnLeaderChanged(fn func(evt LeaderEvent)) error {
+
+	state := meta.EWait
+
+	return b.subscribe(meta.TopicElectionChangeState, func(msg *meta.Message) error {
+		scm := meta.DecodeStateChangeMsg(msg)
+		if scm.ID != b.info.ID || scm.State == state {
+			return nil
+		}
+
+		state = scm.State
+		fn(LeaderEvent{ID: scm.ID, Master: scm.State == meta.EMaster, State: scm.State})
+
+		return nil
+	})
+}
+
+// OnTokenUnlinked 用户的链路信息被移除（用户下线 Unlink，链接的节点下线，链路缓存退出时释放 token
+//
+//	本服务关闭时释放的 token 只会通知到其他服务（本服务的回调在关闭前已经注销
+func (b *Braid) OnTokenUnlinked(fn func(token string)) error {
+	return b.subscribe(meta.TopicLinkcacheUnlinked, func(msg *meta.Message) error {
+		token := string(msg.Body)
+		if token != "" && token != "nil" {
+			fn(token)
+		}
+		return nil
+	})
+}
+
+func (b *Braid) closeEvents() {
+	b.Lock()
+	events := b.events
+	b.events = nil
+	b.Unlock()
+
+	for _, channel := range events {
+		err := channel.Close()
+		if err != nil {
+			b.log.Warnf("[braid] close event channel err %v", err)
+		}
+	}
+}
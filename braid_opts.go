@@ -0,0 +1,49 @@
+package braid
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Parm braid 配置
+type Parm struct {
+	// Run 是否阻塞，直到收到退出信号（或 Run 传入的 context 结束）后关闭 braid
+	WaitSignal bool
+
+	// 阻塞模式下监听的退出信号，默认为 SIGINT, SIGTERM
+	Signals []os.Signal
+
+	// 阻塞模式下关闭 braid 的超时时间
+	ShutdownTimeout time.Duration
+}
+
+// Option config wraps
+type Option func(*Parm)
+
+var (
+	DefaultParm = Parm{
+		Signals:         []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		ShutdownTimeout: time.Second * 30,
+	}
+)
+
+// WithWaitSignal Run 阻塞直到收到退出信号，然后按照依赖顺序关闭 braid
+//
+//	sigs 监听的信号，为空时使用 SIGINT, SIGTERM
+//	grpcserver 默认优雅退出，关闭时会等待处理中的请求完成（见 grpcserver.WithGracefulStopTimeout
+func WithWaitSignal(sigs ...os.Signal) Option {
+	return func(c *Parm) {
+		c.WaitSignal = true
+		if len(sigs) != 0 {
+			c.Signals = sigs
+		}
+	}
+}
+
+// WithShutdownTimeout 阻塞模式下关闭 braid 的超时时间
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *Parm) {
+		c.ShutdownTimeout = timeout
+	}
+}
@@ -1,18 +1,35 @@
 package braid
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	gogoproto "github.com/gogo/protobuf/proto"
 	"github.com/google/uuid"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/pojol/braid-go/components"
+	"github.com/pojol/braid-go/components/discovermemory"
 	"github.com/pojol/braid-go/components/electork8s"
+	"github.com/pojol/braid-go/components/electormemory"
 	"github.com/pojol/braid-go/components/linkcacheredis"
 	"github.com/pojol/braid-go/components/rpcgrpc/grpcclient"
+	"github.com/pojol/braid-go/components/rpcgrpc/grpcgateway"
 	"github.com/pojol/braid-go/components/rpcgrpc/grpcserver"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
 	"github.com/pojol/braid-go/mock"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestMain(m *testing.M) {
@@ -49,7 +66,771 @@ func TestInit(t *testing.T) {
 		},
 	)
 
-	b.Init()
-	b.Run()
-	b.Close()
+	b.Init(context.TODO())
+	b.Run(context.TODO())
+	b.Close(context.TODO())
+}
+
+type routeServer struct {
+	proto.ListenServer
+}
+
+func (rs *routeServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.RouteRes, error) {
+	if string(req.ReqBody) == "slow" {
+		time.Sleep(time.Millisecond * 300)
+	}
+	return &proto.RouteRes{ResBody: req.ReqBody}, nil
+}
+
+// newStandalonePair 创建一组 base (rpc-server) & gate (rpc-client) 服务
+func newStandalonePair(t *testing.T, prefix string, listen string) (*Braid, *Braid) {
+
+	space := components.NewStandalone()
+
+	base, err := NewService(
+		prefix+"_base",
+		uuid.New().String(),
+		components.NewStandaloneDirector(&components.DirectorOpts{
+			Standalone: space,
+			ServerOpts: []grpcserver.Option{
+				grpcserver.WithListen(listen),
+				grpcserver.RegisterHandler(func(srv *grpc.Server) {
+					proto.RegisterListenServer(srv, &routeServer{})
+				}),
+			},
+			MemoryDiscoverOpts: []discovermemory.Option{
+				discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+			},
+		}),
+	)
+	assert.Equal(t, err, nil)
+
+	gate, err := NewService(
+		prefix+"_gate",
+		uuid.New().String(),
+		components.NewStandaloneDirector(&components.DirectorOpts{
+			Standalone: space,
+			MemoryDiscoverOpts: []discovermemory.Option{
+				discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+			},
+		}),
+	)
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, base.Init(context.TODO()), nil)
+	assert.Equal(t, gate.Init(context.TODO()), nil)
+	assert.Equal(t, base.Run(context.TODO()), nil)
+	assert.Equal(t, gate.Run(context.TODO()), nil)
+
+	return base, gate
+}
+
+// waitNodes 等待 b 的负载均衡器中 target 服务的节点数量达到 num
+func waitNodes(t *testing.T, b *Braid, target string, num int) {
+	assert.Eventually(t, func() bool {
+		cnt := 0
+		for _, nod := range b.director.(*components.DefaultDirector).Nodes() {
+			if nod.Name == target {
+				cnt++
+			}
+		}
+		return cnt == num
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestStandalone(t *testing.T) {
+
+	base, gate := newStandalonePair(t, "standalone", ":14301")
+	defer base.Close(context.TODO())
+	defer gate.Close(context.TODO())
+
+	waitNodes(t, gate, "standalone_base", 1)
+
+	res := &proto.RouteRes{}
+	err := gate.Send(context.TODO(), "standalone_base", "/proto.listen/routing", "token", &proto.RouteReq{
+		ReqBody: []byte("ping"),
+	}, res)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.ResBody, []byte("ping"))
+}
+
+func TestMultiInstance(t *testing.T) {
+
+	for i, listen := range []string{":14311", ":14312"} {
+		prefix := fmt.Sprintf("multi%d", i)
+		listen := listen
+
+		t.Run(prefix, func(t *testing.T) {
+			t.Parallel()
+
+			base, gate := newStandalonePair(t, prefix, listen)
+			defer base.Close(context.TODO())
+			defer gate.Close(context.TODO())
+
+			assert.NotEqual(t, base.Logger(), gate.Logger())
+
+			waitNodes(t, gate, prefix+"_base", 1)
+
+			res := &proto.RouteRes{}
+			err := gate.Send(context.TODO(), prefix+"_base", "/proto.listen/routing", "token", &proto.RouteReq{
+				ReqBody: []byte(prefix),
+			}, res)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, res.ResBody, []byte(prefix))
+		})
+	}
+}
+
+func TestDefaultService(t *testing.T) {
+
+	SetDefault(nil)
+	assert.Equal(t, Send(context.TODO(), "", "", "", nil, nil), ErrNoDefaultService)
+
+	first, err := NewService("default_first", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{}))
+	assert.Equal(t, err, nil)
+	second, err := NewService("default_second", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{}))
+	assert.Equal(t, err, nil)
+
+	// 第一个创建的服务作为默认服务
+	assert.Equal(t, Default(), first)
+	assert.Equal(t, Logger(), first.Logger())
+
+	assert.Equal(t, first.Init(context.TODO()), nil)
+	assert.Equal(t, first.Close(context.TODO()), nil)
+	assert.Nil(t, Default())
+
+	SetDefault(second)
+	assert.Equal(t, Default(), second)
+	SetDefault(nil)
+}
+
+func TestWaitSignal(t *testing.T) {
+
+	director := components.NewStandaloneDirector(&components.DirectorOpts{
+		ServerOpts: []grpcserver.Option{
+			grpcserver.WithListen(":14302"),
+			grpcserver.WithGracefulStopTimeout(time.Second),
+			grpcserver.RegisterHandler(func(srv *grpc.Server) {
+				proto.RegisterListenServer(srv, &routeServer{})
+			}),
+		},
+	})
+
+	b, err := NewService(
+		"standalone_signal",
+		uuid.New().String(),
+		director,
+		WithWaitSignal(),
+		WithShutdownTimeout(time.Second*5),
+	)
+	assert.Equal(t, err, nil)
+
+	// 每个模块注册一个依赖它的组件，记录关闭的顺序（上一个 Close 返回之后才会开始下一个
+	order, err := director.Lifecycle().Order()
+	assert.Equal(t, err, nil)
+
+	var mu sync.Mutex
+	var closed []string
+	var closing int32
+	for _, name := range order {
+		name := name
+		assert.Equal(t, director.Lifecycle().Register(components.LifecycleComponent{
+			Name:    "probe." + name,
+			Depends: []string{name},
+			Close: func(ctx context.Context) error {
+				assert.Equal(t, atomic.AddInt32(&closing, 1), int32(1))
+				time.Sleep(time.Millisecond * 5)
+				mu.Lock()
+				closed = append(closed, name)
+				mu.Unlock()
+				atomic.AddInt32(&closing, -1)
+				return nil
+			},
+		}), nil)
+	}
+
+	assert.Equal(t, b.Init(context.TODO()), nil)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("run should block until signal or context done")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	// 退出时处理中的请求可以完成
+	conn, err := grpc.Dial("127.0.0.1:14302", grpc.WithInsecure())
+	assert.Equal(t, err, nil)
+	defer conn.Close()
+
+	rpcerr := make(chan error, 1)
+	res := &proto.RouteRes{}
+	go func() {
+		rpcerr <- conn.Invoke(context.TODO(), "/proto.listen/routing", &proto.RouteReq{ReqBody: []byte("slow")}, res)
+	}()
+	time.Sleep(time.Millisecond * 100)
+
+	cancel()
+
+	select {
+	case err = <-done:
+		assert.Equal(t, err, nil)
+	case <-time.After(time.Second * 5):
+		t.Fatal("shutdown timeout")
+	}
+
+	assert.Equal(t, <-rpcerr, nil)
+	assert.Equal(t, res.ResBody, []byte("slow"))
+
+	// 模块按照初始化的相反顺序关闭
+	reversed := make([]string, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		reversed = append(reversed, order[i])
+	}
+	mu.Lock()
+	assert.Equal(t, closed, reversed)
+	mu.Unlock()
+
+	// 退出后侦听端口已经被释放
+	lis, err := net.Listen("tcp", ":14302")
+	assert.Equal(t, err, nil)
+	lis.Close()
+}
+
+func TestCall(t *testing.T) {
+
+	base, gate := newStandalonePair(t, "call", ":14321")
+	defer base.Close(context.TODO())
+	defer gate.Close(context.TODO())
+
+	waitNodes(t, gate, "call_base", 1)
+
+	routing := NewMethod[proto.RouteReq, proto.RouteRes]("call_base", "/proto.listen/routing")
+
+	res, err := routing.Call(context.TODO(), gate, &proto.RouteReq{ReqBody: []byte("call")},
+		grpcclient.WithCallToken("token"),
+		grpcclient.WithCallTimeout(time.Second),
+	)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.ResBody, []byte("call"))
+
+	res, err = Call[proto.RouteReq, proto.RouteRes](context.TODO(), gate, "call_base", "/proto.listen/routing",
+		&proto.RouteReq{ReqBody: []byte("node")},
+		grpcclient.WithCallStrategy(grpcclient.StrategyRandom),
+		grpcclient.WithCallNode("127.0.0.1:14321"),
+	)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.ResBody, []byte("node"))
+
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{}, grpcclient.WithCallStrategy("unknown"))
+	assert.NotNil(t, err)
+
+	// 不支持的选项类型不再被忽略
+	err = gate.Send(context.TODO(), "call_base", "/proto.listen/routing", "", &proto.RouteReq{}, &proto.RouteRes{}, "opt")
+	assert.NotNil(t, err)
+}
+
+func TestEvents(t *testing.T) {
+
+	space := components.NewStandalone()
+
+	newService := func(name string, opts ...grpcserver.Option) *Braid {
+		b, err := NewService(name, uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+			Standalone: space,
+			ServerOpts: opts,
+			MemoryDiscoverOpts: []discovermemory.Option{
+				discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+			},
+			MemoryElectorOpts: []electormemory.Option{
+				electormemory.WithWatchTick(time.Millisecond * 50),
+			},
+		}))
+		assert.Equal(t, err, nil)
+		return b
+	}
+
+	base := newService("events_base",
+		grpcserver.WithListen(":14331"),
+		grpcserver.RegisterHandler(func(srv *grpc.Server) {
+			proto.RegisterListenServer(srv, &routeServer{})
+		}),
+	)
+	gate := newService("events_gate")
+	defer gate.Close(context.TODO())
+
+	added := make(chan meta.Node, 10)
+	removed := make(chan meta.Node, 10)
+	leader := make(chan LeaderEvent, 10)
+	unlinked := make(chan string, 10)
+
+	assert.Nil(t, gate.OnNodeAdded(func(nod meta.Node) {
+		if nod.Name == "events_base" {
+			added <- nod
+		}
+	}))
+	assert.Nil(t, gate.OnNodeRemoved(func(nod meta.Node) {
+		if nod.Name == "events_base" {
+			removed <- nod
+		}
+	}))
+	assert.Nil(t, base.OnLeaderChanged(func(evt LeaderEvent) { leader <- evt }))
+	assert.Nil(t, gate.OnTokenUnlinked(func(token string) { unlinked <- token }))
+
+	assert.Equal(t, base.Init(context.TODO()), nil)
+	assert.Equal(t, gate.Init(context.TODO()), nil)
+	assert.Equal(t, base.Run(context.TODO()), nil)
+	assert.Equal(t, gate.Run(context.TODO()), nil)
+
+	select {
+	case nod := <-added:
+		assert.Equal(t, nod.ID, base.Info().ID)
+	case <-time.After(time.Second):
+		t.Fatal("node added not arrived")
+	}
+
+	select {
+	case evt := <-leader:
+		assert.True(t, evt.Master)
+		assert.Equal(t, evt.ID, base.Info().ID)
+	case <-time.After(time.Second):
+		t.Fatal("leader changed not arrived")
+	}
+
+	// Run 之后订阅的回调会先收到已知的节点（gate 的负载均衡器中已经有了 base 的节点
+	waitNodes(t, gate, "events_base", 1)
+
+	lateAdded := make(chan meta.Node, 10)
+	lateRemoved := make(chan meta.Node, 10)
+	assert.Nil(t, gate.OnNodeAdded(func(nod meta.Node) {
+		if nod.Name == "events_base" {
+			lateAdded <- nod
+		}
+	}))
+	assert.Nil(t, gate.OnNodeRemoved(func(nod meta.Node) {
+		if nod.Name == "events_base" {
+			lateRemoved <- nod
+		}
+	}))
+	select {
+	case nod := <-lateAdded:
+		assert.Equal(t, nod.ID, base.Info().ID)
+	default:
+		t.Fatal("known node not replayed")
+	}
+
+	// 调用时 gate 的链路缓存中会记录 token 和 base 节点的链路
+	for _, token := range []string{"token", "token_down"} {
+		err := gate.Send(context.TODO(), "events_base", "/proto.listen/routing", token,
+			&proto.RouteReq{ReqBody: []byte(token)}, &proto.RouteRes{})
+		assert.Equal(t, err, nil)
+	}
+
+	// 用户下线，链路缓存移除链路信息之后通知（没有链路信息的 token 不会通知
+	base.Topic(meta.TopicLinkcacheUnlink).Pub(context.TODO(), &meta.Message{Body: []byte("token_unknown")})
+	base.Topic(meta.TopicLinkcacheUnlink).Pub(context.TODO(), &meta.Message{Body: []byte("token")})
+	select {
+	case token := <-unlinked:
+		assert.Equal(t, token, "token")
+	case <-time.After(time.Second):
+		t.Fatal("token unlinked not arrived")
+	}
+
+	// 链接的节点下线时链路缓存移除的 token 也会通知
+	assert.Equal(t, base.Close(context.TODO()), nil)
+	select {
+	case nod := <-removed:
+		assert.Equal(t, nod.ID, base.Info().ID)
+	case <-time.After(time.Second):
+		t.Fatal("node removed not arrived")
+	}
+	select {
+	case token := <-unlinked:
+		assert.Equal(t, token, "token_down")
+	case <-time.After(time.Second):
+		t.Fatal("token unlinked by node down not arrived")
+	}
+	select {
+	case nod := <-lateRemoved:
+		assert.Equal(t, nod.ID, base.Info().ID)
+	case <-time.After(time.Second):
+		t.Fatal("late node removed not arrived")
+	}
+
+	// 重复的事件和关闭后的事件不会再回调
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, len(added), 0)
+	assert.Equal(t, len(lateAdded), 0)
+	assert.Equal(t, len(leader), 0)
+	assert.Equal(t, len(unlinked), 0)
+}
+
+type flakyServer struct {
+	proto.ListenServer
+
+	fails int32
+	code  codes.Code
+	calls int32
+	delay time.Duration
+}
+
+func (fs *flakyServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.RouteRes, error) {
+	time.Sleep(fs.delay)
+	if atomic.AddInt32(&fs.calls, 1) <= atomic.LoadInt32(&fs.fails) {
+		return nil, status.Error(fs.code, "flaky")
+	}
+	return &proto.RouteRes{ResBody: req.ReqBody}, nil
+}
+
+// echoStream 双向流，将收到的消息原样返回
+var echoStreamDesc = grpc.StreamDesc{
+	StreamName:    "echo",
+	ServerStreams: true,
+	ClientStreams: true,
+	Handler: func(srv interface{}, stream grpc.ServerStream) error {
+		for {
+			req := &proto.RouteReq{}
+			err := stream.RecvMsg(req)
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			err = stream.SendMsg(&proto.RouteRes{ResBody: req.ReqBody})
+			if err != nil {
+				return err
+			}
+		}
+	},
+}
+
+func TestStream(t *testing.T) {
+
+	space := components.NewStandalone()
+
+	var serverStreams, clientStreams int32
+
+	base, err := NewService("stream_base", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+		Standalone: space,
+		ServerOpts: []grpcserver.Option{
+			grpcserver.WithListen(":14361"),
+			grpcserver.AppendStreamInterceptors(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				atomic.AddInt32(&serverStreams, 1)
+				return handler(srv, ss)
+			}),
+			grpcserver.RegisterHandler(func(srv *grpc.Server) {
+				srv.RegisterService(&grpc.ServiceDesc{
+					ServiceName: "test.stream",
+					HandlerType: (*interface{})(nil),
+					Streams:     []grpc.StreamDesc{echoStreamDesc},
+				}, nil)
+			}),
+		},
+		MemoryDiscoverOpts: []discovermemory.Option{
+			discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+		},
+	}))
+	assert.Equal(t, err, nil)
+
+	gate, err := NewService("stream_gate", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+		Standalone: space,
+		ClientOpts: []grpcclient.Option{
+			grpcclient.AppendStreamInterceptors(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				atomic.AddInt32(&clientStreams, 1)
+				return streamer(ctx, desc, cc, method, opts...)
+			}),
+		},
+		MemoryDiscoverOpts: []discovermemory.Option{
+			discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+		},
+	}))
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, base.Init(context.TODO()), nil)
+	assert.Equal(t, gate.Init(context.TODO()), nil)
+	assert.Equal(t, base.Run(context.TODO()), nil)
+	assert.Equal(t, gate.Run(context.TODO()), nil)
+	defer base.Close(context.TODO())
+	defer gate.Close(context.TODO())
+
+	waitNodes(t, gate, "stream_base", 1)
+
+	stream, err := gate.NewStream(context.TODO(), "stream_base", "/test.stream/echo", "token", &echoStreamDesc,
+		grpcclient.WithCallTimeout(time.Second*5),
+	)
+	assert.Equal(t, err, nil)
+
+	for _, body := range []string{"a", "b", "c"} {
+		assert.Equal(t, stream.SendMsg(&proto.RouteReq{ReqBody: []byte(body)}), nil)
+
+		res := &proto.RouteRes{}
+		assert.Equal(t, stream.RecvMsg(res), nil)
+		assert.Equal(t, res.ResBody, []byte(body))
+	}
+
+	assert.Equal(t, stream.CloseSend(), nil)
+	assert.Equal(t, stream.RecvMsg(&proto.RouteRes{}), io.EOF)
+
+	assert.Equal(t, atomic.LoadInt32(&clientStreams), int32(1))
+	assert.Equal(t, atomic.LoadInt32(&serverStreams), int32(1))
+}
+
+func TestBroadcast(t *testing.T) {
+
+	space := components.NewStandalone()
+
+	servers := []*flakyServer{{}, {}, {fails: 100, code: codes.Internal}}
+
+	for i, fs := range servers {
+		fs := fs
+		base, err := NewService("broadcast_base", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+			Standalone: space,
+			ServerOpts: []grpcserver.Option{
+				grpcserver.WithListen(fmt.Sprintf(":%d", 14391+i)),
+				grpcserver.RegisterHandler(func(srv *grpc.Server) {
+					proto.RegisterListenServer(srv, fs)
+				}),
+			},
+			MemoryDiscoverOpts: []discovermemory.Option{
+				discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+			},
+		}))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, base.Init(context.TODO()), nil)
+		assert.Equal(t, base.Run(context.TODO()), nil)
+		defer base.Close(context.TODO())
+	}
+
+	gate, err := NewService("broadcast_gate", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+		Standalone: space,
+		MemoryDiscoverOpts: []discovermemory.Option{
+			discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+		},
+	}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, gate.Init(context.TODO()), nil)
+	assert.Equal(t, gate.Run(context.TODO()), nil)
+	defer gate.Close(context.TODO())
+
+	waitNodes(t, gate, "broadcast_base", 3)
+
+	routing := NewMethod[proto.RouteReq, proto.RouteRes]("broadcast_base", "/proto.listen/routing")
+
+	// 默认需要所有节点成功
+	results, err := routing.CallAll(context.TODO(), gate, &proto.RouteReq{ReqBody: []byte("all")},
+		grpcclient.WithCallTimeout(time.Second))
+	assert.True(t, errors.Is(err, grpcclient.ErrQuorumNotReached))
+	assert.Equal(t, len(results), 3)
+
+	succeeded := 0
+	for _, res := range results {
+		if res.Err == nil {
+			succeeded++
+			assert.Equal(t, string(res.Reply.ResBody), "all")
+		} else {
+			assert.Equal(t, status.Code(res.Err), codes.Internal)
+		}
+	}
+	assert.Equal(t, succeeded, 2)
+
+	for _, fs := range servers {
+		assert.Equal(t, atomic.LoadInt32(&fs.calls), int32(1))
+	}
+
+	results, err = routing.CallAll(context.TODO(), gate, &proto.RouteReq{},
+		grpcclient.WithCallTimeout(time.Second), grpcclient.WithCallQuorum(2))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(results), 3)
+
+	_, err = CallAll[proto.RouteReq, proto.RouteRes](context.TODO(), gate, "broadcast_unknown", "/proto.listen/routing", &proto.RouteReq{})
+	assert.True(t, errors.Is(err, grpcclient.ErrCantFindNode))
+}
+
+func TestCallNode(t *testing.T) {
+
+	space := components.NewStandalone()
+
+	servers := []*flakyServer{{}, {}}
+	bases := []*Braid{}
+
+	for i, fs := range servers {
+		fs := fs
+		base, err := NewService("pin_base", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+			Standalone: space,
+			ServerOpts: []grpcserver.Option{
+				grpcserver.WithListen(fmt.Sprintf(":%d", 14401+i)),
+				grpcserver.RegisterHandler(func(srv *grpc.Server) {
+					proto.RegisterListenServer(srv, fs)
+				}),
+			},
+			MemoryDiscoverOpts: []discovermemory.Option{
+				discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+			},
+		}))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, base.Init(context.TODO()), nil)
+		assert.Equal(t, base.Run(context.TODO()), nil)
+		defer base.Close(context.TODO())
+
+		bases = append(bases, base)
+	}
+
+	gate, err := NewService("pin_gate", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+		Standalone: space,
+		MemoryDiscoverOpts: []discovermemory.Option{
+			discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+		},
+	}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, gate.Init(context.TODO()), nil)
+	assert.Equal(t, gate.Run(context.TODO()), nil)
+	defer gate.Close(context.TODO())
+
+	waitNodes(t, gate, "pin_base", 2)
+
+	routing := NewMethod[proto.RouteReq, proto.RouteRes]("pin_base", "/proto.listen/routing")
+
+	for i := 0; i < 5; i++ {
+		_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{}, grpcclient.WithCallNodeID(bases[1].Info().ID))
+		assert.Equal(t, err, nil)
+	}
+	assert.Equal(t, atomic.LoadInt32(&servers[0].calls), int32(0))
+	assert.Equal(t, atomic.LoadInt32(&servers[1].calls), int32(5))
+
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{}, grpcclient.WithCallNode("127.0.0.1:14401"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, atomic.LoadInt32(&servers[0].calls), int32(1))
+
+	// 节点不存在
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{}, grpcclient.WithCallNodeID("unknown"))
+	var nf *grpcclient.NodeNotFoundError
+	assert.True(t, errors.As(err, &nf))
+	assert.Equal(t, nf.Node, "unknown")
+	assert.True(t, errors.Is(err, grpcclient.ErrCantFindNode))
+
+	// token 先通过负载均衡链接到一个节点
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{}, grpcclient.WithCallToken("pin_token"))
+	assert.Equal(t, err, nil)
+
+	other := 0
+	if atomic.LoadInt32(&servers[0].calls) == 2 {
+		other = 1
+	}
+	before := atomic.LoadInt32(&servers[other].calls)
+
+	// 指定节点并记录到 token 的链路信息中，之后不指定节点的调用也路由到这个节点
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{},
+		grpcclient.WithCallToken("pin_token"),
+		grpcclient.WithCallNodeID(bases[other].Info().ID),
+		grpcclient.WithCallLink(),
+	)
+	assert.Equal(t, err, nil)
+
+	for i := 0; i < 5; i++ {
+		_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{}, grpcclient.WithCallToken("pin_token"))
+		assert.Equal(t, err, nil)
+	}
+	assert.Equal(t, atomic.LoadInt32(&servers[other].calls), before+6)
+}
+
+// metaServer 返回请求的 body 以及收到的元数据
+type metaServer struct {
+	proto.ListenServer
+}
+
+func (ms *metaServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.RouteRes, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("uid")) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "uid not found")
+	}
+	return &proto.RouteRes{ResBody: append(req.ReqBody, []byte(" "+md.Get("uid")[0])...)}, nil
+}
+
+func TestRouteGateway(t *testing.T) {
+
+	space := components.NewStandalone()
+
+	base, err := NewService("gw_base", uuid.New().String(), components.NewStandaloneDirector(&components.DirectorOpts{
+		Standalone: space,
+		ServerOpts: []grpcserver.Option{
+			grpcserver.WithListen(":14431"),
+			grpcserver.RegisterHandler(func(srv *grpc.Server) {
+				proto.RegisterListenServer(srv, &metaServer{})
+			}),
+		},
+		MemoryDiscoverOpts: []discovermemory.Option{
+			discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+		},
+	}))
+	assert.Equal(t, err, nil)
+
+	var director *components.DefaultDirector
+	director = components.NewStandaloneDirector(&components.DirectorOpts{
+		Standalone: space,
+		ServerOpts: []grpcserver.Option{
+			grpcserver.WithListen(":14432"),
+			grpcserver.RegisterHandler(func(srv *grpc.Server) {
+				proto.RegisterListenServer(srv, grpcgateway.BuildWithOption(
+					meta.ServiceInfo{Name: "gw_gate"}, director.Logger(), director.Client(),
+					grpcgateway.WithServices("gw_base"),
+				))
+			}),
+		},
+		MemoryDiscoverOpts: []discovermemory.Option{
+			discovermemory.WithSyncServiceInterval(time.Millisecond * 50),
+		},
+	})
+	gate, err := NewService("gw_gate", uuid.New().String(), director)
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, base.Init(context.TODO()), nil)
+	assert.Equal(t, gate.Init(context.TODO()), nil)
+	assert.Equal(t, base.Run(context.TODO()), nil)
+	assert.Equal(t, gate.Run(context.TODO()), nil)
+	defer base.Close(context.TODO())
+	defer gate.Close(context.TODO())
+
+	waitNodes(t, gate, "gw_base", 1)
+	waitNodes(t, gate, "gw_gate", 1)
+
+	body, err := gogoproto.Marshal(&proto.RouteReq{ReqBody: []byte("hello")})
+	assert.Equal(t, err, nil)
+
+	routing := NewMethod[proto.RouteReq, proto.RouteRes]("gw_gate", "/proto.listen/routing")
+
+	res, err := routing.Call(context.TODO(), gate, &proto.RouteReq{
+		Nod:     "gw_base",
+		Service: "proto.listen/routing",
+		Token:   "gw_token",
+		ReqBody: body,
+		Meta:    []*proto.Header{{Key: "uid", Val: "1001"}},
+	})
+	assert.Equal(t, err, nil)
+
+	inner := &proto.RouteRes{}
+	assert.Equal(t, gogoproto.Unmarshal(res.ResBody, inner), nil)
+	assert.Equal(t, string(inner.ResBody), "hello 1001")
+
+	// 目标服务返回的错误码
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{
+		Nod:     "gw_base",
+		Service: "/proto.listen/routing",
+		ReqBody: body,
+	})
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+
+	// 不允许转发的服务
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{
+		Nod:     "gw_gate",
+		Service: "/proto.listen/routing",
+	})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+
+	_, err = routing.Call(context.TODO(), gate, &proto.RouteReq{Nod: "gw_base"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
 }
@@ -13,3 +13,10 @@ type AgentCheck struct {
 	Type        string
 	Namespace   string `json:",omitempty"`
 }
+
+// CheckUpdateTTL 更新 TTL 检查的状态（consul.HealthPassing, HealthWarning, HealthCritical
+//
+//	https://developer.hashicorp.com/consul/api-docs/agent/check#ttl-check-update
+func (c *Client) CheckUpdateTTL(checkID, output, status string) error {
+	return c.Client().Agent().UpdateTTL(checkID, output, status)
+}
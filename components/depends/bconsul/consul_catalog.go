@@ -58,11 +58,21 @@ func (c *Client) CatalogGetService(name string) (meta.Service, error) {
 			continue
 		}
 
-		service.Nodes = append(service.Nodes, meta.Node{
+		nod := meta.Node{
 			ID:      s.Service.ID,
 			Address: s.Service.Address,
 			Port:    s.Service.Port,
-		})
+		}
+
+		// 注册时附带的元数据（如 weight
+		if len(s.Service.Meta) != 0 {
+			nod.Metadata = make(map[string]interface{}, len(s.Service.Meta))
+			for k, v := range s.Service.Meta {
+				nod.Metadata[k] = v
+			}
+		}
+
+		service.Nodes = append(service.Nodes, nod)
 
 	}
 
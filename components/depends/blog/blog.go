@@ -3,6 +3,7 @@ package blog
 import (
 	"errors"
 	"os"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -27,7 +28,8 @@ const (
 )
 
 var (
-	logPtr *Logger
+	// 最后一次创建的日志（兼容包级别的 Close
+	last atomic.Value
 
 	// ErrConfigConvert 配置转换失败
 	ErrConfigConvert = errors.New("convert linker config")
@@ -44,35 +46,46 @@ var (
 	}
 )
 
+// BuildWithOption 基于 DefaultConfig 的拷贝创建日志，不会修改 DefaultConfig
 func BuildWithOption(opts ...Option) *Logger {
 
-	logParm := &DefaultConfig
+	logParm := DefaultConfig
 
 	for _, opt := range opts {
-		opt(logParm)
+		opt(&logParm)
 	}
 
-	return new(logParm)
+	return new(&logParm)
 }
 
 func BuildWithDefaultOption() *Logger {
-	return new(&DefaultConfig)
+	logParm := DefaultConfig
+	return new(&logParm)
 }
 
 func new(parm *Parm) *Logger {
-	logPtr = &Logger{}
+	log := &Logger{}
 
-	logPtr.normalLog = newlog(parm)
-	logPtr.normalSugared = logPtr.normalLog.Sugar()
+	log.normalLog = newlog(parm)
+	log.normalSugared = log.normalLog.Sugar()
 
-	return logPtr
+	last.Store(log)
+
+	return log
+}
+
+// Close 将缓存中的日志刷新到文件
+func (l *Logger) Close() {
+	l.normalLog.Sync()
 }
 
-// Close 清理日志，并将缓存中的日志刷新到文件
+// Close 将最后一次创建的日志缓存中的日志刷新到文件
+//
+// Deprecated: 每个服务的日志由 director 在 Close 时刷新，其他日志使用 Logger.Close
 func Close() {
 
-	if logPtr != nil {
-		logPtr.normalLog.Sync()
+	if log, ok := last.Load().(*Logger); ok {
+		log.Close()
 	}
 
 }
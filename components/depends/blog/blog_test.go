@@ -1,13 +1,31 @@
 package blog
 
 import (
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLog(t *testing.T) {
-
-	BuildWithOption()
+	log := BuildWithOption()
 	defer Close()
 
-	logPtr.Infof("msg %v", 1)
+	log.Infof("msg %v", 1)
+}
+
+func TestMultiLog(t *testing.T) {
+
+	def := DefaultConfig
+
+	debug := BuildWithOption(WithLevel(int(DebugLevel)), WithPath(filepath.Join(t.TempDir(), "debug")))
+	info := BuildWithOption()
+	defer debug.Close()
+	defer info.Close()
+
+	// 创建日志不会修改默认配置
+	assert.Equal(t, DefaultConfig, def)
+
+	assert.True(t, debug.normalLog.Core().Enabled(DebugLevel))
+	assert.False(t, info.normalLog.Core().Enabled(DebugLevel))
 }
@@ -1,18 +1,25 @@
 package components
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/pojol/braid-go/components/depends/bconsul"
 	"github.com/pojol/braid-go/components/depends/bk8s"
 	"github.com/pojol/braid-go/components/depends/blog"
 	"github.com/pojol/braid-go/components/depends/bredis"
+	"github.com/pojol/braid-go/components/discoverconsul"
 	"github.com/pojol/braid-go/components/discoverk8s"
+	"github.com/pojol/braid-go/components/discovermemory"
+	"github.com/pojol/braid-go/components/discoverstatic"
+	"github.com/pojol/braid-go/components/electorconsul"
 	"github.com/pojol/braid-go/components/electork8s"
+	"github.com/pojol/braid-go/components/electormemory"
 	"github.com/pojol/braid-go/components/internal/balancer"
 	"github.com/pojol/braid-go/components/linkcacheredis"
 	"github.com/pojol/braid-go/components/monitorredis"
-	"github.com/pojol/braid-go/components/pubsubredis"
+	"github.com/pojol/braid-go/components/pubsubnsq"
 	"github.com/pojol/braid-go/components/rpcgrpc/grpcclient"
 	"github.com/pojol/braid-go/components/rpcgrpc/grpcserver"
 	"github.com/pojol/braid-go/module"
@@ -25,9 +32,11 @@ type IDirector interface {
 
 	SetServiceInfo(info meta.ServiceInfo)
 
-	Init() error
-	Run()
-	Close()
+	Init(ctx context.Context) error
+	Run(ctx context.Context) error
+	Close(ctx context.Context) error
+
+	Health(ctx context.Context) HealthReport
 
 	Logger() *blog.Logger
 
@@ -46,6 +55,46 @@ type DirectorOpts struct {
 	ElectorOpts   []electork8s.Option
 	LinkcacheOpts []linkcacheredis.Option
 	DiscoverOpts  []discoverk8s.Option
+
+	NsqOpts            []pubsubnsq.Option
+	ConsulDiscoverOpts []discoverconsul.Option
+	ConsulElectorOpts  []electorconsul.Option
+	MonitorOpts        []monitorredis.MqWatchOption
+	MemoryDiscoverOpts []discovermemory.Option
+	StaticDiscoverOpts []discoverstatic.Option
+	MemoryElectorOpts  []electormemory.Option
+
+	// 模块实现的名称（如 PubsubNsq, DiscoverConsul ...
+	// 为空时使用默认的实现 pubsubredis, discoverk8s, electork8s, linkcacheredis, monitorredis
+	// 设置为 None 时不构建这个模块（pubsub 除外
+	Pubsub    string
+	Discover  string
+	Elector   string
+	Linkcache string
+	Monitor   string
+
+	// 自定义的模块构建函数，设置后优先于名称选择
+	PubsubFactory    PubsubFactory
+	DiscoverFactory  DiscoverFactory
+	ElectorFactory   ElectorFactory
+	LinkcacheFactory LinkcacheFactory
+	MonitorFactory   MonitorFactory
+
+	// 基于进程内存实现的模块（pubsubmemory, discovermemory ...）共享的状态，为空时每个 director 单独创建
+	// 同一进程中需要互相发现、调用的服务使用同一个 NewStandalone()，或者使用全局的 GlobalStandalone()
+	Standalone *Standalone
+
+	// 模块 Init / Run / Close 各个阶段的超时时间，为空时使用 DefaultLifecycleTimeouts
+	LifecycleTimeouts LifecycleTimeouts
+
+	// server 启动时将自身注册到 consul（使用 ConsulCliOpts 创建的客户端），退出时注销
+	// 注册的地址、权重、健康检查方式等通过 ServerOpts 设置（grpcserver.WithRegisterAddress ...
+	ConsulRegister bool
+
+	// 健康检查 http 服务的侦听地址（如 :8081），为空时不启动；也可以通过 DefaultDirector.HealthHandler 自行挂载
+	HealthAddr string
+	// http 健康检查的超时时间，为 0 时使用 DefaultHealthTimeout
+	HealthTimeout time.Duration
 }
 
 type DefaultDirector struct {
@@ -55,6 +104,13 @@ type DefaultDirector struct {
 
 	log *blog.Logger
 
+	rediscli  *redis.Client
+	k8scli    *bk8s.Client
+	consulcli *bconsul.Client
+
+	// 内存模块共享的状态（见 DirectorOpts.Standalone
+	memory *Standalone
+
 	monitor module.IMonitor
 
 	client module.IClient
@@ -66,6 +122,15 @@ type DefaultDirector struct {
 	linkcache module.ILinkCache
 	pubsub    module.IPubsub
 	elector   module.IElector
+
+	lifecycle *Lifecycle
+}
+
+// DefaultLifecycleTimeouts 没有设置 DirectorOpts.LifecycleTimeouts 时使用的超时时间
+var DefaultLifecycleTimeouts = LifecycleTimeouts{
+	Init:  time.Second * 10,
+	Run:   time.Second * 10,
+	Close: time.Second * 10,
 }
 
 type Component func(*DefaultDirector)
@@ -88,150 +153,293 @@ func (d *DefaultDirector) SetServiceInfo(info meta.ServiceInfo) {
 
 func (d *DefaultDirector) Build() error {
 
+	if d.Opts == nil {
+		d.Opts = &DirectorOpts{}
+	}
+
 	d.log = blog.BuildWithOption(d.Opts.LogOpts...)
-	var rediscli *redis.Client
-	var k8scli *bk8s.Client
 
-	if d.Opts.RedisCliOpts != nil {
-		rediscli = bredis.BuildWithOption(d.Opts.RedisCliOpts)
-	} else {
-		rediscli = bredis.BuildWithDefault()
+	ps, err := d.buildPubsub()
+	if err != nil {
+		return fmt.Errorf("pubsub build err : %w", err)
 	}
+	d.pubsub = ps
 
-	if len(d.Opts.K8sCliOpts) != 0 {
-		k8scli = bk8s.BuildWithOption(d.Opts.K8sCliOpts...)
-	} else {
-		k8scli = bk8s.BuildWithOption(bk8s.WithConfigPath(""))
+	d.discovery, err = d.buildDiscover()
+	if err != nil {
+		return fmt.Errorf("discovery build err : %w", err)
 	}
 
-	ps := pubsubredis.BuildWithOption(d.info, d.log, rediscli)
-
-	discover := discoverk8s.BuildWithOption(
-		d.info,
-		d.log,
-		k8scli,
-		ps,
-		d.Opts.DiscoverOpts...,
-	)
-
-	lc := linkcacheredis.BuildWithOption(d.info, d.log, ps, rediscli, d.Opts.LinkcacheOpts...)
+	d.linkcache, err = d.buildLinkcache()
+	if err != nil {
+		return fmt.Errorf("linkcache build err : %w", err)
+	}
 
-	elector := electork8s.BuildWithOption(d.info, d.log, ps, k8scli, d.Opts.ElectorOpts...)
+	d.elector, err = d.buildElector()
+	if err != nil {
+		return fmt.Errorf("elector build err : %w", err)
+	}
 
-	// tmp
-	e := monitorredis.BuildWithOption(d.log, rediscli)
+	d.monitor, err = d.buildMonitor()
+	if err != nil {
+		return fmt.Errorf("monitor build err : %w", err)
+	}
 
-	d.pubsub = ps
-	d.linkcache = lc
-	d.discovery = discover
-	d.elector = elector
 	d.balancer = balancer.BuildWithOption(d.info, d.log, ps)
-	d.monitor = e
 
 	d.client = grpcclient.BuildWithOption(
 		d.info,
 		d.log,
 		d.balancer,
-		lc,
+		d.linkcache,
 		ps,
 		d.Opts.ClientOpts...,
 	)
 
 	if len(d.Opts.ServerOpts) != 0 {
-		d.server = grpcserver.BuildWithOption(d.info, d.log, d.Opts.ServerOpts...)
+		// 配置了 server（如配置文件中的 server.listen）但是没有在代码中设置 handler
+		if d.serverParm().Handler == nil {
+			return &ConfigError{Key: "server.listen", Err: fmt.Errorf("rpc server handler not set, use grpcserver.RegisterHandler")}
+		}
+
+		serverOpts := d.Opts.ServerOpts
+		if d.Opts.ConsulRegister {
+			serverOpts = append([]grpcserver.Option{grpcserver.WithRegister(d.ConsulClient())}, serverOpts...)
+		}
+		d.server = grpcserver.BuildWithOption(d.info, d.log, serverOpts...)
+	}
+
+	err = d.buildLifecycle()
+	if err != nil {
+		return fmt.Errorf("lifecycle build err : %w", err)
 	}
 
 	return nil
 }
 
-func (d *DefaultDirector) Init() error {
-
-	var err error
-
-	if d.server != nil {
-		err = d.server.Init()
-		if err != nil {
-			return fmt.Errorf("server init err : %w", err)
+// RedisClient 获取 redis 客户端，只有在第一次使用时才会创建
+func (d *DefaultDirector) RedisClient() *redis.Client {
+	if d.rediscli == nil {
+		if d.Opts.RedisCliOpts != nil {
+			d.rediscli = bredis.BuildWithOption(d.Opts.RedisCliOpts)
+		} else {
+			d.rediscli = bredis.BuildWithDefault()
 		}
 	}
+	return d.rediscli
+}
 
-	if d.client != nil {
-		err = d.client.Init()
-		if err != nil {
-			return fmt.Errorf("client init err : %w", err)
+// K8sClient 获取 k8s 客户端，只有在第一次使用时才会创建
+func (d *DefaultDirector) K8sClient() *bk8s.Client {
+	if d.k8scli == nil {
+		if len(d.Opts.K8sCliOpts) != 0 {
+			d.k8scli = bk8s.BuildWithOption(d.Opts.K8sCliOpts...)
+		} else {
+			d.k8scli = bk8s.BuildWithOption(bk8s.WithConfigPath(""))
 		}
 	}
+	return d.k8scli
+}
 
-	if d.discovery != nil {
-		err = d.discovery.Init()
-		if err != nil {
-			return fmt.Errorf("discovery init err : %w", err)
-		}
+// ConsulClient 获取 consul 客户端，只有在第一次使用时才会创建
+func (d *DefaultDirector) ConsulClient() *bconsul.Client {
+	if d.consulcli == nil {
+		d.consulcli = bconsul.BuildWithOption(d.Opts.ConsulCliOpts...)
 	}
+	return d.consulcli
+}
 
-	if d.elector != nil {
-		err = d.elector.Init()
-		if err != nil {
-			return fmt.Errorf("elector init err : %w", err)
-		}
+// buildLifecycle 注册已经构建好的模块以及模块之间的依赖关系
+//
+//	depends -> pubsub -> balancer -> linkcache -> client -> elector -> server -> discovery -> monitor
+//	discovery 需要在 balancer, client, linkcache 订阅之后再广播节点信息
+//	关闭时按照相反的顺序：先注销服务发现，再停止 server（等待处理中的请求），之后释放选举锁和链路缓存中的 token，
+//	最后关闭 pubsub 的订阅以及 redis 等外部服务的客户端
+func (d *DefaultDirector) buildLifecycle() error {
+
+	timeouts := d.Opts.LifecycleTimeouts
+	if timeouts == (LifecycleTimeouts{}) {
+		timeouts = DefaultLifecycleTimeouts
 	}
+	d.lifecycle = NewLifecycle(timeouts)
 
-	if d.linkcache != nil {
-		err = d.linkcache.Init()
-		if err != nil {
-			return fmt.Errorf("linkcache init err : %w", err)
-		}
+	lst := []LifecycleComponent{
+		{
+			Name:  meta.ModuleDepends,
+			Close: voidFn(d.closeDepends),
+		},
 	}
 
-	return nil
-}
+	if d.Opts.HealthAddr != "" {
+		lst = append(lst, d.healthComponent())
+	}
+
+	lst = append(lst, []LifecycleComponent{
+		{
+			Name:    meta.ModulePubsub,
+			Depends: []string{meta.ModuleDepends},
+			Close:   closeFn(d.pubsub),
+			Health:  healthFn(d.pubsub),
+		},
+		{
+			Name:    meta.ModuleBalancer,
+			Depends: []string{meta.ModulePubsub},
+			Init:    voidFn(d.balancer.Init),
+			Run:     voidFn(d.balancer.Run),
+			Close:   voidFn(d.balancer.Close),
+			Health:  healthFn(d.balancer),
+		},
+	}...)
 
-func (d *DefaultDirector) Run() {
+	if d.linkcache != nil {
+		lst = append(lst, LifecycleComponent{
+			Name:    meta.ModuleLink,
+			Depends: []string{meta.ModulePubsub},
+			Init:    errFn(d.linkcache.Init),
+			Run:     voidFn(d.linkcache.Run),
+			Close:   voidFn(d.linkcache.Close),
+			Health:  healthFn(d.linkcache),
+		})
+	}
 
-	d.monitor.Run()
+	lst = append(lst, LifecycleComponent{
+		Name:    meta.ModuleClient,
+		Depends: []string{meta.ModulePubsub, meta.ModuleBalancer, meta.ModuleLink},
+		Init:    errFn(d.client.Init),
+		Close:   voidFn(d.client.Close),
+		Health:  healthFn(d.client),
+	})
 
 	if d.server != nil {
-		d.server.Run()
+		lst = append(lst, LifecycleComponent{
+			Name:    meta.ModuleServer,
+			Depends: []string{meta.ModuleClient, meta.ModuleLink, meta.ModuleElector},
+			Init:    errFn(d.server.Init),
+			Run:     voidFn(d.server.Run),
+			Close:   voidFn(d.server.Close),
+			Health:  healthFn(d.server),
+		})
+	}
+
+	if d.elector != nil {
+		lst = append(lst, LifecycleComponent{
+			Name:    meta.ModuleElector,
+			Depends: []string{meta.ModulePubsub},
+			Init:    errFn(d.elector.Init),
+			Run:     voidFn(d.elector.Run),
+			Close:   voidFn(d.elector.Close),
+			Health:  healthFn(d.elector),
+		})
 	}
 
 	if d.discovery != nil {
-		d.discovery.Run()
+		lst = append(lst, LifecycleComponent{
+			Name:    meta.ModuleDiscover,
+			Depends: []string{meta.ModuleBalancer, meta.ModuleLink, meta.ModuleClient, meta.ModuleServer},
+			Init:    errFn(d.discovery.Init),
+			Run:     voidFn(d.discovery.Run),
+			Close:   voidFn(d.discovery.Close),
+			Health:  healthFn(d.discovery),
+		})
 	}
 
-	if d.elector != nil {
-		d.elector.Run()
+	if d.monitor != nil {
+		lst = append(lst, LifecycleComponent{
+			Name:   meta.ModuleMonitor,
+			Run:    voidFn(d.monitor.Run),
+			Health: healthFn(d.monitor),
+		})
 	}
 
-	if d.linkcache != nil {
-		d.linkcache.Run()
+	registered := make(map[string]bool)
+	for _, c := range lst {
+		registered[c.Name] = true
 	}
-}
 
-func (d *DefaultDirector) Close() {
+	for _, c := range lst {
+		// 未构建的模块不作为依赖
+		depends := []string{}
+		for _, dep := range c.Depends {
+			if registered[dep] {
+				depends = append(depends, dep)
+			}
+		}
+		c.Depends = depends
 
-	d.balancer.Close()
+		err := d.lifecycle.Register(c)
+		if err != nil {
+			return err
+		}
+	}
 
-	if d.server != nil {
-		d.server.Close()
+	return nil
+}
+
+// closeDepends 关闭已经创建的外部服务客户端（所有模块关闭之后
+func (d *DefaultDirector) closeDepends() {
+	if d.rediscli != nil {
+		err := d.rediscli.Close()
+		if err != nil {
+			d.log.Warnf("[braid.director] close redis client err %s", err.Error())
+		}
 	}
+}
 
-	if d.discovery != nil {
-		d.discovery.Close()
+// closeFn 模块实现了 Close 时返回关闭函数（如 pubsub
+func closeFn(m interface{}) func(ctx context.Context) error {
+	if c, ok := m.(interface{ Close() }); ok {
+		return voidFn(c.Close)
 	}
+	return nil
+}
 
-	if d.elector != nil {
-		d.elector.Close()
+func errFn(fn func() error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return fn()
 	}
+}
 
-	if d.linkcache != nil {
-		d.linkcache.Close()
+// healthFn 模块实现了 module.IHealth 时返回健康检查函数
+func healthFn(m interface{}) func(ctx context.Context) module.HealthStatus {
+	if h, ok := m.(module.IHealth); ok {
+		return h.Health
 	}
+	return nil
+}
 
-	if d.client != nil {
-		d.client.Close()
+func voidFn(fn func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		fn()
+		return nil
 	}
 }
 
+// Lifecycle 获取模块的生命周期管理器，可以在 Init 之前注册自定义的组件
+func (d *DefaultDirector) Lifecycle() *Lifecycle {
+	return d.lifecycle
+}
+
+// Init 按照依赖顺序初始化模块，失败时会关闭已经初始化的模块
+func (d *DefaultDirector) Init(ctx context.Context) error {
+	return d.lifecycle.Init(ctx)
+}
+
+// Run 按照依赖顺序运行模块，失败时会关闭所有模块
+func (d *DefaultDirector) Run(ctx context.Context) error {
+	return d.lifecycle.Run(ctx)
+}
+
+// Close 按照相反的顺序关闭模块，返回所有模块关闭时产生的错误（CloseErrors），最后刷新日志
+func (d *DefaultDirector) Close(ctx context.Context) error {
+	err := d.lifecycle.Close(ctx)
+	d.log.Close()
+	return err
+}
+
+func (d *DefaultDirector) ServiceInfo() meta.ServiceInfo {
+	return d.info
+}
+
 func (d *DefaultDirector) Logger() *blog.Logger {
 	return d.log
 }
@@ -243,3 +451,11 @@ func (d *DefaultDirector) Client() module.IClient {
 func (d *DefaultDirector) Pubsub() module.IPubsub {
 	return d.pubsub
 }
+
+// Nodes 负载均衡器中当前已知的所有服务节点
+func (d *DefaultDirector) Nodes() []meta.Node {
+	if d.balancer == nil {
+		return nil
+	}
+	return d.balancer.All()
+}
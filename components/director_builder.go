@@ -0,0 +1,242 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pojol/braid-go/components/discoverconsul"
+	"github.com/pojol/braid-go/components/discoverk8s"
+	"github.com/pojol/braid-go/components/discovermemory"
+	"github.com/pojol/braid-go/components/discoverstatic"
+	"github.com/pojol/braid-go/components/electorconsul"
+	"github.com/pojol/braid-go/components/electork8s"
+	"github.com/pojol/braid-go/components/electormemory"
+	"github.com/pojol/braid-go/components/linkcachememory"
+	"github.com/pojol/braid-go/components/linkcacheredis"
+	"github.com/pojol/braid-go/components/monitorredis"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/components/pubsubnsq"
+	"github.com/pojol/braid-go/components/pubsubredis"
+	"github.com/pojol/braid-go/components/rpcgrpc/grpcserver"
+	"github.com/pojol/braid-go/module"
+)
+
+// 模块实现名称，用于在 DirectorOpts 中选择模块所使用的实现
+const (
+	// None 不构建这个模块
+	None = "none"
+
+	PubsubRedis  = "pubsubredis"
+	PubsubNsq    = "pubsubnsq"
+	PubsubMemory = "pubsubmemory"
+
+	DiscoverK8s    = "discoverk8s"
+	DiscoverConsul = "discoverconsul"
+	DiscoverMemory = "discovermemory"
+	DiscoverStatic = "discoverstatic"
+
+	ElectorK8s    = "electork8s"
+	ElectorConsul = "electorconsul"
+	ElectorMemory = "electormemory"
+
+	LinkcacheRedis  = "linkcacheredis"
+	LinkcacheMemory = "linkcachememory"
+
+	MonitorRedis = "monitorredis"
+)
+
+// 模块构建函数，在 Build 阶段被调用；可以通过 DefaultDirector 获取服务信息、日志以及已经构建好的 pubsub
+type (
+	PubsubFactory    func(d *DefaultDirector) (module.IPubsub, error)
+	DiscoverFactory  func(d *DefaultDirector) (module.IDiscover, error)
+	ElectorFactory   func(d *DefaultDirector) (module.IElector, error)
+	LinkcacheFactory func(d *DefaultDirector) (module.ILinkCache, error)
+	MonitorFactory   func(d *DefaultDirector) (module.IMonitor, error)
+)
+
+var (
+	pubsubFactories = map[string]PubsubFactory{
+		PubsubRedis: func(d *DefaultDirector) (module.IPubsub, error) {
+			return pubsubredis.BuildWithOption(d.info, d.log, d.RedisClient()), nil
+		},
+		PubsubNsq: func(d *DefaultDirector) (module.IPubsub, error) {
+			return pubsubnsq.BuildWithOption(d.info, d.log, d.Opts.NsqOpts...), nil
+		},
+		PubsubMemory: func(d *DefaultDirector) (module.IPubsub, error) {
+			return pubsubmemory.BuildWithOption(d.info, d.log, pubsubmemory.WithBroker(d.standalone().Broker)), nil
+		},
+	}
+
+	discoverFactories = map[string]DiscoverFactory{
+		DiscoverK8s: func(d *DefaultDirector) (module.IDiscover, error) {
+			return discoverk8s.BuildWithOption(d.info, d.log, d.K8sClient(), d.pubsub, d.Opts.DiscoverOpts...), nil
+		},
+		DiscoverConsul: func(d *DefaultDirector) (module.IDiscover, error) {
+			return discoverconsul.BuildWithOption(d.info, d.log, d.ConsulClient(), d.pubsub, d.Opts.ConsulDiscoverOpts...), nil
+		},
+		DiscoverMemory: func(d *DefaultDirector) (module.IDiscover, error) {
+			opts := []discovermemory.Option{discovermemory.WithRegistry(d.standalone().Registry)}
+			if addr := d.serverAddr(); addr != "" {
+				opts = append(opts, discovermemory.WithAddress(addr))
+			}
+			return discovermemory.BuildWithOption(d.info, d.log, d.pubsub, append(opts, d.Opts.MemoryDiscoverOpts...)...), nil
+		},
+		DiscoverStatic: func(d *DefaultDirector) (module.IDiscover, error) {
+			return discoverstatic.BuildWithOption(d.info, d.log, d.pubsub, d.Opts.StaticDiscoverOpts...), nil
+		},
+	}
+
+	electorFactories = map[string]ElectorFactory{
+		ElectorK8s: func(d *DefaultDirector) (module.IElector, error) {
+			return electork8s.BuildWithOption(d.info, d.log, d.pubsub, d.K8sClient(), d.Opts.ElectorOpts...), nil
+		},
+		ElectorConsul: func(d *DefaultDirector) (module.IElector, error) {
+			opts := []electorconsul.Option{
+				electorconsul.WithLog(d.log),
+				electorconsul.WithPubsub(d.pubsub),
+				electorconsul.WithConsulClient(d.ConsulClient()),
+			}
+			return electorconsul.BuildWithOption(d.info, append(opts, d.Opts.ConsulElectorOpts...)...), nil
+		},
+		ElectorMemory: func(d *DefaultDirector) (module.IElector, error) {
+			opts := []electormemory.Option{electormemory.WithLeases(d.standalone().Leases)}
+			return electormemory.BuildWithOption(d.info, d.log, d.pubsub, append(opts, d.Opts.MemoryElectorOpts...)...), nil
+		},
+	}
+
+	linkcacheFactories = map[string]LinkcacheFactory{
+		LinkcacheRedis: func(d *DefaultDirector) (module.ILinkCache, error) {
+			return linkcacheredis.BuildWithOption(d.info, d.log, d.pubsub, d.RedisClient(), d.Opts.LinkcacheOpts...), nil
+		},
+		LinkcacheMemory: func(d *DefaultDirector) (module.ILinkCache, error) {
+			return linkcachememory.BuildWithOption(d.info, d.log, d.pubsub, linkcachememory.WithStore(d.standalone().Store)), nil
+		},
+	}
+
+	monitorFactories = map[string]MonitorFactory{
+		MonitorRedis: func(d *DefaultDirector) (module.IMonitor, error) {
+			return monitorredis.BuildWithOption(d.log, d.RedisClient(), d.Opts.MonitorOpts...), nil
+		},
+	}
+)
+
+// serverParm rpc-server 的配置
+func (d *DefaultDirector) serverParm() grpcserver.Parm {
+	p := grpcserver.DefaultServerParm
+	for _, opt := range d.Opts.ServerOpts {
+		opt(&p)
+	}
+	return p
+}
+
+// serverAddr 从 rpc-server 的配置中获取可以被访问到的侦听地址，没有配置 server 时返回空
+func (d *DefaultDirector) serverAddr() string {
+	if len(d.Opts.ServerOpts) == 0 {
+		return ""
+	}
+
+	p := d.serverParm()
+
+	if strings.HasPrefix(p.ListenAddr, ":") {
+		return "127.0.0.1" + p.ListenAddr
+	}
+
+	return p.ListenAddr
+}
+
+func selectName(name string, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+func (d *DefaultDirector) buildPubsub() (module.IPubsub, error) {
+	if d.Opts.PubsubFactory != nil {
+		return d.Opts.PubsubFactory(d)
+	}
+
+	name := selectName(d.Opts.Pubsub, PubsubRedis)
+	if name == None {
+		return nil, fmt.Errorf("pubsub is required by balancer & client, can't be %v", None)
+	}
+
+	f, ok := pubsubFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pubsub implementation %v", name)
+	}
+
+	return f(d)
+}
+
+func (d *DefaultDirector) buildDiscover() (module.IDiscover, error) {
+	if d.Opts.DiscoverFactory != nil {
+		return d.Opts.DiscoverFactory(d)
+	}
+
+	name := selectName(d.Opts.Discover, DiscoverK8s)
+	if name == None {
+		return nil, nil
+	}
+
+	f, ok := discoverFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown discover implementation %v", name)
+	}
+
+	return f(d)
+}
+
+func (d *DefaultDirector) buildElector() (module.IElector, error) {
+	if d.Opts.ElectorFactory != nil {
+		return d.Opts.ElectorFactory(d)
+	}
+
+	name := selectName(d.Opts.Elector, ElectorK8s)
+	if name == None {
+		return nil, nil
+	}
+
+	f, ok := electorFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown elector implementation %v", name)
+	}
+
+	return f(d)
+}
+
+func (d *DefaultDirector) buildLinkcache() (module.ILinkCache, error) {
+	if d.Opts.LinkcacheFactory != nil {
+		return d.Opts.LinkcacheFactory(d)
+	}
+
+	name := selectName(d.Opts.Linkcache, LinkcacheRedis)
+	if name == None {
+		return nil, nil
+	}
+
+	f, ok := linkcacheFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown linkcache implementation %v", name)
+	}
+
+	return f(d)
+}
+
+func (d *DefaultDirector) buildMonitor() (module.IMonitor, error) {
+	if d.Opts.MonitorFactory != nil {
+		return d.Opts.MonitorFactory(d)
+	}
+
+	name := selectName(d.Opts.Monitor, MonitorRedis)
+	if name == None {
+		return nil, nil
+	}
+
+	f, ok := monitorFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown monitor implementation %v", name)
+	}
+
+	return f(d)
+}
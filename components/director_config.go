@@ -0,0 +1,625 @@
+package components
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pojol/braid-go/components/depends/bconsul"
+	"github.com/pojol/braid-go/components/depends/bk8s"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/discoverconsul"
+	"github.com/pojol/braid-go/components/discoverk8s"
+	"github.com/pojol/braid-go/components/discovermemory"
+	"github.com/pojol/braid-go/components/discoverstatic"
+	"github.com/pojol/braid-go/components/electorconsul"
+	"github.com/pojol/braid-go/components/electork8s"
+	"github.com/pojol/braid-go/components/electormemory"
+	"github.com/pojol/braid-go/components/linkcacheredis"
+	"github.com/pojol/braid-go/components/rpcgrpc/grpcserver"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnvPrefix 环境变量的前缀，环境变量名由前缀和配置的路径组成（如 BRAID_REDIS_ADDR, BRAID_ELECTOR_WATCH_TICK
+//
+//	[]string 使用逗号分隔（a,b,c），map[string]int 使用 name=value 的格式（base=14222,login=14223
+const ConfigEnvPrefix = "BRAID"
+
+// ConfigError 配置错误，Key 为配置的路径（如 elector.watch_tick）或环境变量名
+type ConfigError struct {
+	Key string
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config %v err : %v", e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// DirectorConfig 可以从 yaml / toml 文件中加载的 DirectorOpts 配置
+//
+//	modules:
+//	  pubsub: pubsubredis
+//	  discover: discoverk8s
+//	redis:
+//	  addr: 127.0.0.1:6379
+//	elector:
+//	  watch_tick: 2s
+//	discover:
+//	  namespace: default
+//	  ports:
+//	    base: 14222
+type DirectorConfig struct {
+	Modules   ModulesConfig   `yaml:"modules" toml:"modules"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Redis     RedisConfig     `yaml:"redis" toml:"redis"`
+	K8s       K8sConfig       `yaml:"k8s" toml:"k8s"`
+	Consul    ConsulConfig    `yaml:"consul" toml:"consul"`
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Elector   ElectorConfig   `yaml:"elector" toml:"elector"`
+	Linkcache LinkcacheConfig `yaml:"linkcache" toml:"linkcache"`
+	Discover  DiscoverConfig  `yaml:"discover" toml:"discover"`
+	Health    HealthConfig    `yaml:"health" toml:"health"`
+	Lifecycle LifecycleConfig `yaml:"lifecycle" toml:"lifecycle"`
+}
+
+// ModulesConfig 模块实现的名称（见 DirectorOpts.Pubsub ...
+type ModulesConfig struct {
+	Pubsub    string `yaml:"pubsub" toml:"pubsub"`
+	Discover  string `yaml:"discover" toml:"discover"`
+	Elector   string `yaml:"elector" toml:"elector"`
+	Linkcache string `yaml:"linkcache" toml:"linkcache"`
+	Monitor   string `yaml:"monitor" toml:"monitor"`
+}
+
+type LogConfig struct {
+	// debug, info, warn, error
+	Level    string `yaml:"level" toml:"level"`
+	Path     string `yaml:"path" toml:"path"`
+	Suffex   string `yaml:"suffex" toml:"suffex"`
+	MaxSize  int    `yaml:"max_size" toml:"max_size"`
+	Backups  int    `yaml:"backups" toml:"backups"`
+	MaxAge   int    `yaml:"max_age" toml:"max_age"`
+	Stdout   bool   `yaml:"stdout" toml:"stdout"`
+	Compress bool   `yaml:"compress" toml:"compress"`
+}
+
+// RedisConfig 设置 addr 后才会替换默认的 redis 配置
+type RedisConfig struct {
+	Addr         string        `yaml:"addr" toml:"addr"`
+	Username     string        `yaml:"username" toml:"username"`
+	Password     string        `yaml:"password" toml:"password"`
+	DB           int           `yaml:"db" toml:"db"`
+	PoolSize     int           `yaml:"pool_size" toml:"pool_size"`
+	DialTimeout  time.Duration `yaml:"dial_timeout" toml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+}
+
+type K8sConfig struct {
+	ConfigPath string `yaml:"config_path" toml:"config_path"`
+}
+
+type ConsulConfig struct {
+	Address []string      `yaml:"address" toml:"address"`
+	Token   string        `yaml:"token" toml:"token"`
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// ServerConfig rpc-server 的配置，handler 仍然需要在代码中通过 grpcserver.RegisterHandler 设置（没有设置时 Build 返回 *ConfigError
+type ServerConfig struct {
+	Listen              string        `yaml:"listen" toml:"listen"`
+	GracefulStopTimeout time.Duration `yaml:"graceful_stop_timeout" toml:"graceful_stop_timeout"`
+
+	// 注册 grpc 反射服务（见 grpcserver.WithReflection
+	Reflection bool `yaml:"reflection" toml:"reflection"`
+	// 注册 grpc.health.v1.Health 服务（见 grpcserver.WithHealth
+	GRPCHealth bool `yaml:"grpc_health" toml:"grpc_health"`
+
+	// 启动时将 server 注册到 consul（见 DirectorOpts.ConsulRegister
+	Register        bool   `yaml:"register" toml:"register"`
+	RegisterAddress string `yaml:"register_address" toml:"register_address"`
+	RegisterWeight  int    `yaml:"register_weight" toml:"register_weight"`
+}
+
+// ElectorConfig 选举配置
+//
+//	watch_tick   尝试获取锁的间隔（electorconsul 的 LockTick
+//	refresh_tick 续租的间隔（electorconsul 的 SessionTick
+type ElectorConfig struct {
+	Namespace   string        `yaml:"namespace" toml:"namespace"`
+	Name        string        `yaml:"name" toml:"name"`
+	WatchTick   time.Duration `yaml:"watch_tick" toml:"watch_tick"`
+	RefreshTick time.Duration `yaml:"refresh_tick" toml:"refresh_tick"`
+}
+
+type LinkcacheConfig struct {
+	// local, redis
+	Mode string `yaml:"mode" toml:"mode"`
+}
+
+type DiscoverConfig struct {
+	Namespace    string         `yaml:"namespace" toml:"namespace"`
+	Tag          string         `yaml:"tag" toml:"tag"`
+	Blacklist    []string       `yaml:"blacklist" toml:"blacklist"`
+	SyncInterval time.Duration  `yaml:"sync_interval" toml:"sync_interval"`
+	Ports        map[string]int `yaml:"ports" toml:"ports"`
+	// discoverstatic 使用的节点描述文件
+	File string `yaml:"file" toml:"file"`
+}
+
+type HealthConfig struct {
+	Addr    string        `yaml:"addr" toml:"addr"`
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+}
+
+type LifecycleConfig struct {
+	InitTimeout  time.Duration `yaml:"init_timeout" toml:"init_timeout"`
+	RunTimeout   time.Duration `yaml:"run_timeout" toml:"run_timeout"`
+	CloseTimeout time.Duration `yaml:"close_timeout" toml:"close_timeout"`
+}
+
+var (
+	logLevels = map[string]int{
+		"debug": int(blog.DebugLevel),
+		"info":  int(blog.InfoLevel),
+		"warn":  int(blog.WarnLevel),
+		"error": int(blog.ErrLevel),
+	}
+
+	linkcacheModes = map[string]string{
+		"local":                             linkcacheredis.LinkerRedisModeLocal,
+		"redis":                             linkcacheredis.LinkerRedisModeRedis,
+		linkcacheredis.LinkerRedisModeLocal: linkcacheredis.LinkerRedisModeLocal,
+		linkcacheredis.LinkerRedisModeRedis: linkcacheredis.LinkerRedisModeRedis,
+	}
+)
+
+// LoadDirectorOpts 从 yaml / toml 文件加载 DirectorOpts，并使用 BRAID_ 开头的环境变量覆盖
+//
+//	path 为空时只使用环境变量
+func LoadDirectorOpts(path string) (*DirectorOpts, error) {
+	cfg, err := LoadDirectorConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.DirectorOpts(), nil
+}
+
+// LoadDirectorConfig 从 yaml / toml 文件加载配置，使用环境变量覆盖后进行校验
+func LoadDirectorConfig(path string) (*DirectorConfig, error) {
+
+	cfg := &DirectorConfig{}
+
+	if path != "" {
+		byt, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			dec := yaml.NewDecoder(bytes.NewReader(byt))
+			dec.KnownFields(true)
+			err = dec.Decode(cfg)
+			if err != nil && !errors.Is(err, io.EOF) {
+				if key := unknownYamlKey(byt, reflect.TypeOf(cfg).Elem()); key != "" {
+					return nil, &ConfigError{Key: key, Err: fmt.Errorf("unknown key")}
+				}
+				return nil, fmt.Errorf("config %v decode err : %w", path, err)
+			}
+		case ".toml":
+			md, err := toml.Decode(string(byt), cfg)
+			if err != nil {
+				return nil, fmt.Errorf("config %v decode err : %w", path, err)
+			}
+			if undecoded := md.Undecoded(); len(undecoded) != 0 {
+				return nil, &ConfigError{Key: undecoded[0].String(), Err: fmt.Errorf("unknown key")}
+			}
+		default:
+			return nil, fmt.Errorf("config %v unsupported format, use .yaml .yml or .toml", path)
+		}
+	}
+
+	err := cfg.applyEnv(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// unknownYamlKey 查找 yaml 中没有对应字段的 key，返回 yaml 标签组成的路径（没有时返回空
+func unknownYamlKey(byt []byte, t reflect.Type) string {
+
+	var doc yaml.Node
+	if yaml.Unmarshal(byt, &doc) != nil || len(doc.Content) == 0 {
+		return ""
+	}
+
+	var find func(node *yaml.Node, t reflect.Type, path []string) string
+	find = func(node *yaml.Node, t reflect.Type, path []string) string {
+		if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+			return ""
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := append(append([]string{}, path...), node.Content[i].Value)
+
+			field, ok := yamlField(t, node.Content[i].Value)
+			if !ok {
+				return strings.Join(key, ".")
+			}
+
+			if k := find(node.Content[i+1], field.Type, key); k != "" {
+				return k
+			}
+		}
+
+		return ""
+	}
+
+	return find(doc.Content[0], t, nil)
+}
+
+// yamlField 通过 yaml 标签查找结构体的字段
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0] == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// configFields 遍历配置中的所有字段，key 为 yaml 标签组成的路径
+func configFields(v reflect.Value, path []string, fn func(key []string, field reflect.Value) error) error {
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := append(append([]string{}, path...), strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0])
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			err := configFields(field, key, fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := fn(key, field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyEnv 使用环境变量覆盖配置
+func (cfg *DirectorConfig) applyEnv(lookup func(string) (string, bool)) error {
+
+	durationType := reflect.TypeOf(time.Duration(0))
+
+	return configFields(reflect.ValueOf(cfg).Elem(), nil, func(key []string, field reflect.Value) error {
+
+		env := ConfigEnvPrefix + "_" + strings.ToUpper(strings.Join(key, "_"))
+		val, ok := lookup(env)
+		if !ok {
+			return nil
+		}
+
+		switch {
+		case field.Type() == durationType:
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return &ConfigError{Key: env, Err: err}
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.String:
+			field.SetString(val)
+		case field.Kind() == reflect.Int:
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return &ConfigError{Key: env, Err: err}
+			}
+			field.SetInt(int64(n))
+		case field.Kind() == reflect.Bool:
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return &ConfigError{Key: env, Err: err}
+			}
+			field.SetBool(b)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			lst := []string{}
+			for _, s := range strings.Split(val, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					lst = append(lst, s)
+				}
+			}
+			field.Set(reflect.ValueOf(lst))
+		case field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.Int:
+			m := make(map[string]int)
+			for _, pair := range strings.Split(val, ",") {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 {
+					return &ConfigError{Key: env, Err: fmt.Errorf("%v should be name=value", pair)}
+				}
+
+				n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+				if err != nil {
+					return &ConfigError{Key: env, Err: err}
+				}
+				m[strings.TrimSpace(kv[0])] = n
+			}
+			field.Set(reflect.ValueOf(m))
+		default:
+			return &ConfigError{Key: env, Err: fmt.Errorf("unsupported type %v", field.Type())}
+		}
+
+		return nil
+	})
+}
+
+func validateModule(key string, name string, registered bool) error {
+	if name == "" || name == None || registered {
+		return nil
+	}
+	return &ConfigError{Key: key, Err: fmt.Errorf("unknown implementation %v", name)}
+}
+
+func validateAddr(key string, addr string) error {
+	if addr == "" {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return &ConfigError{Key: key, Err: err}
+	}
+
+	if _, err = strconv.Atoi(port); err != nil {
+		return &ConfigError{Key: key, Err: fmt.Errorf("invalid port %v", port)}
+	}
+
+	return nil
+}
+
+// Validate 校验配置，返回的错误为 *ConfigError
+func (cfg *DirectorConfig) Validate() error {
+
+	if cfg.Modules.Pubsub == None {
+		return &ConfigError{Key: "modules.pubsub", Err: fmt.Errorf("pubsub can't be %v", None)}
+	}
+
+	_, ok := pubsubFactories[cfg.Modules.Pubsub]
+	if err := validateModule("modules.pubsub", cfg.Modules.Pubsub, ok); err != nil {
+		return err
+	}
+	_, ok = discoverFactories[cfg.Modules.Discover]
+	if err := validateModule("modules.discover", cfg.Modules.Discover, ok); err != nil {
+		return err
+	}
+	_, ok = electorFactories[cfg.Modules.Elector]
+	if err := validateModule("modules.elector", cfg.Modules.Elector, ok); err != nil {
+		return err
+	}
+	_, ok = linkcacheFactories[cfg.Modules.Linkcache]
+	if err := validateModule("modules.linkcache", cfg.Modules.Linkcache, ok); err != nil {
+		return err
+	}
+	_, ok = monitorFactories[cfg.Modules.Monitor]
+	if err := validateModule("modules.monitor", cfg.Modules.Monitor, ok); err != nil {
+		return err
+	}
+
+	if _, ok := logLevels[cfg.Log.Level]; cfg.Log.Level != "" && !ok {
+		return &ConfigError{Key: "log.level", Err: fmt.Errorf("unknown level %v, use debug, info, warn or error", cfg.Log.Level)}
+	}
+
+	if _, ok := linkcacheModes[cfg.Linkcache.Mode]; cfg.Linkcache.Mode != "" && !ok {
+		return &ConfigError{Key: "linkcache.mode", Err: fmt.Errorf("unknown mode %v, use local or redis", cfg.Linkcache.Mode)}
+	}
+
+	if err := validateAddr("redis.addr", cfg.Redis.Addr); err != nil {
+		return err
+	}
+	if err := validateAddr("server.listen", cfg.Server.Listen); err != nil {
+		return err
+	}
+	if err := validateAddr("health.addr", cfg.Health.Addr); err != nil {
+		return err
+	}
+
+	for name, port := range cfg.Discover.Ports {
+		if port <= 0 || port > 65535 {
+			return &ConfigError{Key: "discover.ports." + name, Err: fmt.Errorf("port %v out of range", port)}
+		}
+	}
+
+	// 数值类型的配置不能为负数
+	return configFields(reflect.ValueOf(cfg).Elem(), nil, func(key []string, field reflect.Value) error {
+		if field.Kind() == reflect.Int || field.Kind() == reflect.Int64 {
+			if field.Int() < 0 {
+				return &ConfigError{Key: strings.Join(key, "."), Err: fmt.Errorf("can't be negative")}
+			}
+		}
+		return nil
+	})
+}
+
+// DirectorOpts 将配置转换为 DirectorOpts，需要在代码中设置的选项（如 rpc handler，拦截器）可以继续追加到返回的 DirectorOpts 中
+func (cfg *DirectorConfig) DirectorOpts() *DirectorOpts {
+
+	opts := &DirectorOpts{
+		Pubsub:    cfg.Modules.Pubsub,
+		Discover:  cfg.Modules.Discover,
+		Elector:   cfg.Modules.Elector,
+		Linkcache: cfg.Modules.Linkcache,
+		Monitor:   cfg.Modules.Monitor,
+
+		HealthAddr:    cfg.Health.Addr,
+		HealthTimeout: cfg.Health.Timeout,
+
+		LifecycleTimeouts: LifecycleTimeouts{
+			Init:  cfg.Lifecycle.InitTimeout,
+			Run:   cfg.Lifecycle.RunTimeout,
+			Close: cfg.Lifecycle.CloseTimeout,
+		},
+	}
+
+	// log
+	if cfg.Log.Level != "" {
+		opts.LogOpts = append(opts.LogOpts, blog.WithLevel(logLevels[cfg.Log.Level]))
+	}
+	if cfg.Log.Path != "" {
+		opts.LogOpts = append(opts.LogOpts, blog.WithPath(cfg.Log.Path))
+	}
+	if cfg.Log.Suffex != "" {
+		opts.LogOpts = append(opts.LogOpts, blog.WithSuffex(cfg.Log.Suffex))
+	}
+	if cfg.Log.MaxSize != 0 {
+		opts.LogOpts = append(opts.LogOpts, blog.WithMaxSize(cfg.Log.MaxSize))
+	}
+	if cfg.Log.Backups != 0 {
+		opts.LogOpts = append(opts.LogOpts, blog.WithBackups(cfg.Log.Backups))
+	}
+	if cfg.Log.MaxAge != 0 {
+		opts.LogOpts = append(opts.LogOpts, blog.WithMaxAge(cfg.Log.MaxAge))
+	}
+	if cfg.Log.Stdout {
+		opts.LogOpts = append(opts.LogOpts, blog.WithStdout(true))
+	}
+	if cfg.Log.Compress {
+		opts.LogOpts = append(opts.LogOpts, blog.WithCompress(true))
+	}
+
+	// redis
+	if cfg.Redis.Addr != "" {
+		opts.RedisCliOpts = &redis.Options{
+			Addr:         cfg.Redis.Addr,
+			Username:     cfg.Redis.Username,
+			Password:     cfg.Redis.Password,
+			DB:           cfg.Redis.DB,
+			PoolSize:     cfg.Redis.PoolSize,
+			DialTimeout:  cfg.Redis.DialTimeout,
+			ReadTimeout:  cfg.Redis.ReadTimeout,
+			WriteTimeout: cfg.Redis.WriteTimeout,
+		}
+	}
+
+	// k8s & consul
+	if cfg.K8s.ConfigPath != "" {
+		opts.K8sCliOpts = append(opts.K8sCliOpts, bk8s.WithConfigPath(cfg.K8s.ConfigPath))
+	}
+	if len(cfg.Consul.Address) != 0 {
+		opts.ConsulCliOpts = append(opts.ConsulCliOpts, bconsul.WithAddress(cfg.Consul.Address))
+	}
+	if cfg.Consul.Token != "" {
+		opts.ConsulCliOpts = append(opts.ConsulCliOpts, bconsul.WithToken(cfg.Consul.Token))
+	}
+	if cfg.Consul.Timeout != 0 {
+		opts.ConsulCliOpts = append(opts.ConsulCliOpts, bconsul.WithTimeOut(cfg.Consul.Timeout))
+	}
+
+	// server
+	if cfg.Server.Listen != "" {
+		opts.ServerOpts = append(opts.ServerOpts, grpcserver.WithListen(cfg.Server.Listen))
+	}
+	if cfg.Server.GracefulStopTimeout != 0 {
+		opts.ServerOpts = append(opts.ServerOpts, grpcserver.WithGracefulStopTimeout(cfg.Server.GracefulStopTimeout))
+	}
+	if cfg.Server.GRPCHealth {
+		opts.ServerOpts = append(opts.ServerOpts, grpcserver.WithHealth())
+	}
+	if cfg.Server.Reflection {
+		opts.ServerOpts = append(opts.ServerOpts, grpcserver.WithReflection())
+	}
+	opts.ConsulRegister = cfg.Server.Register
+	if cfg.Server.RegisterAddress != "" {
+		opts.ServerOpts = append(opts.ServerOpts, grpcserver.WithRegisterAddress(cfg.Server.RegisterAddress))
+	}
+	if cfg.Server.RegisterWeight != 0 {
+		opts.ServerOpts = append(opts.ServerOpts, grpcserver.WithRegisterWeight(cfg.Server.RegisterWeight))
+	}
+
+	// elector
+	if cfg.Elector.Namespace != "" {
+		opts.ElectorOpts = append(opts.ElectorOpts, electork8s.WithNamespace(cfg.Elector.Namespace))
+	}
+	if cfg.Elector.Name != "" {
+		opts.ElectorOpts = append(opts.ElectorOpts, electork8s.WithName(cfg.Elector.Name))
+	}
+	if cfg.Elector.WatchTick != 0 {
+		opts.ElectorOpts = append(opts.ElectorOpts, electork8s.WithWatchTick(cfg.Elector.WatchTick))
+		opts.ConsulElectorOpts = append(opts.ConsulElectorOpts, electorconsul.WithLockTick(cfg.Elector.WatchTick))
+		opts.MemoryElectorOpts = append(opts.MemoryElectorOpts, electormemory.WithWatchTick(cfg.Elector.WatchTick))
+	}
+	if cfg.Elector.RefreshTick != 0 {
+		opts.ElectorOpts = append(opts.ElectorOpts, electork8s.WithRefreshTick(cfg.Elector.RefreshTick))
+		opts.ConsulElectorOpts = append(opts.ConsulElectorOpts, electorconsul.WithSessionTick(cfg.Elector.RefreshTick))
+	}
+
+	// linkcache
+	if cfg.Linkcache.Mode != "" {
+		opts.LinkcacheOpts = append(opts.LinkcacheOpts, linkcacheredis.WithMode(linkcacheModes[cfg.Linkcache.Mode]))
+	}
+
+	// discover
+	if cfg.Discover.Namespace != "" {
+		opts.DiscoverOpts = append(opts.DiscoverOpts, discoverk8s.WithNamespace(cfg.Discover.Namespace))
+	}
+	if cfg.Discover.Tag != "" {
+		opts.DiscoverOpts = append(opts.DiscoverOpts, discoverk8s.WithSelectorTag(cfg.Discover.Tag))
+		opts.ConsulDiscoverOpts = append(opts.ConsulDiscoverOpts, discoverconsul.WithTag(cfg.Discover.Tag))
+		opts.MemoryDiscoverOpts = append(opts.MemoryDiscoverOpts, discovermemory.WithTag(cfg.Discover.Tag))
+	}
+	if len(cfg.Discover.Blacklist) != 0 {
+		opts.DiscoverOpts = append(opts.DiscoverOpts, discoverk8s.WithBlacklist(cfg.Discover.Blacklist))
+		opts.ConsulDiscoverOpts = append(opts.ConsulDiscoverOpts, discoverconsul.WithBlacklist(cfg.Discover.Blacklist))
+		opts.MemoryDiscoverOpts = append(opts.MemoryDiscoverOpts, discovermemory.WithBlacklist(cfg.Discover.Blacklist))
+		opts.StaticDiscoverOpts = append(opts.StaticDiscoverOpts, discoverstatic.WithBlacklist(cfg.Discover.Blacklist))
+	}
+	if cfg.Discover.SyncInterval != 0 {
+		opts.DiscoverOpts = append(opts.DiscoverOpts, discoverk8s.WithSyncServiceInterval(cfg.Discover.SyncInterval))
+		opts.ConsulDiscoverOpts = append(opts.ConsulDiscoverOpts, discoverconsul.WithSyncServiceInterval(cfg.Discover.SyncInterval))
+		opts.MemoryDiscoverOpts = append(opts.MemoryDiscoverOpts, discovermemory.WithSyncServiceInterval(cfg.Discover.SyncInterval))
+		opts.StaticDiscoverOpts = append(opts.StaticDiscoverOpts, discoverstatic.WithSyncServiceInterval(cfg.Discover.SyncInterval))
+	}
+	if len(cfg.Discover.Ports) != 0 {
+		pairs := []discoverk8s.ServicePortPair{}
+		for name, port := range cfg.Discover.Ports {
+			pairs = append(pairs, discoverk8s.ServicePortPair{Name: name, Port: port})
+		}
+		opts.DiscoverOpts = append(opts.DiscoverOpts, discoverk8s.WithServicePortPairs(pairs))
+	}
+	if cfg.Discover.File != "" {
+		opts.StaticDiscoverOpts = append(opts.StaticDiscoverOpts, discoverstatic.WithFile(cfg.Discover.File))
+	}
+
+	return opts
+}
@@ -0,0 +1,162 @@
+package components
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadDirectorConfigYaml(t *testing.T) {
+
+	path := writeConfig(t, "braid.yaml", `
+modules:
+  pubsub: pubsubredis
+  discover: discoverk8s
+log:
+  level: warn
+  stdout: true
+redis:
+  addr: 127.0.0.1:6379
+  db: 2
+  dial_timeout: 3s
+elector:
+  watch_tick: 2s
+linkcache:
+  mode: local
+discover:
+  namespace: game
+  ports:
+    base: 14222
+`)
+
+	cfg, err := LoadDirectorConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "pubsubredis", cfg.Modules.Pubsub)
+	assert.Equal(t, 2, cfg.Redis.DB)
+	assert.Equal(t, time.Second*3, cfg.Redis.DialTimeout)
+	assert.Equal(t, time.Second*2, cfg.Elector.WatchTick)
+	assert.Equal(t, map[string]int{"base": 14222}, cfg.Discover.Ports)
+
+	opts := cfg.DirectorOpts()
+	assert.Equal(t, "discoverk8s", opts.Discover)
+	assert.Equal(t, "127.0.0.1:6379", opts.RedisCliOpts.Addr)
+	assert.Equal(t, 2, len(opts.LogOpts))
+	assert.Equal(t, 1, len(opts.LinkcacheOpts))
+	assert.Equal(t, 1, len(opts.ElectorOpts))
+	assert.Equal(t, 2, len(opts.DiscoverOpts))
+}
+
+func TestLoadDirectorConfigToml(t *testing.T) {
+
+	path := writeConfig(t, "braid.toml", `
+[modules]
+pubsub = "pubsubmemory"
+
+[server]
+listen = ":14222"
+graceful_stop_timeout = "5s"
+
+[discover]
+blacklist = ["gate"]
+`)
+
+	opts, err := LoadDirectorOpts(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "pubsubmemory", opts.Pubsub)
+	assert.Equal(t, 2, len(opts.ServerOpts))
+	assert.Equal(t, 1, len(opts.StaticDiscoverOpts))
+}
+
+func TestDirectorConfigEnv(t *testing.T) {
+
+	path := writeConfig(t, "braid.yaml", `
+redis:
+  addr: 127.0.0.1:6379
+`)
+
+	t.Setenv("BRAID_REDIS_ADDR", "10.0.0.1:6379")
+	t.Setenv("BRAID_ELECTOR_WATCH_TICK", "500ms")
+	t.Setenv("BRAID_LOG_STDOUT", "true")
+	t.Setenv("BRAID_CONSUL_ADDRESS", "http://a:8500, http://b:8500")
+	t.Setenv("BRAID_DISCOVER_PORTS", "base=14222,login=14223")
+
+	cfg, err := LoadDirectorConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1:6379", cfg.Redis.Addr)
+	assert.Equal(t, time.Millisecond*500, cfg.Elector.WatchTick)
+	assert.True(t, cfg.Log.Stdout)
+	assert.Equal(t, []string{"http://a:8500", "http://b:8500"}, cfg.Consul.Address)
+	assert.Equal(t, map[string]int{"base": 14222, "login": 14223}, cfg.Discover.Ports)
+}
+
+func TestDirectorConfigErrors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		key     string
+	}{
+		{"unknown yaml key", "a.yaml", "redis:\n  adr: 127.0.0.1:6379\n", nil, "redis.adr"},
+		{"unknown yaml section", "a.yaml", "redis:\n  addr: 127.0.0.1:6379\nredix:\n  addr: 127.0.0.1:6379\n", nil, "redix"},
+		{"unknown toml key", "a.toml", "[redis]\nadr = \"127.0.0.1:6379\"\n", nil, "redis.adr"},
+		{"module", "a.yaml", "modules:\n  discover: discoverzk\n", nil, "modules.discover"},
+		{"pubsub none", "a.yaml", "modules:\n  pubsub: none\n", nil, "modules.pubsub"},
+		{"level", "a.yaml", "log:\n  level: trace\n", nil, "log.level"},
+		{"mode", "a.yaml", "linkcache:\n  mode: disk\n", nil, "linkcache.mode"},
+		{"listen", "a.yaml", "server:\n  listen: 14222\n", nil, "server.listen"},
+		{"port", "a.yaml", "discover:\n  ports:\n    base: 70000\n", nil, "discover.ports.base"},
+		{"negative", "a.yaml", "elector:\n  watch_tick: -1s\n", nil, "elector.watch_tick"},
+		{"env duration", "a.yaml", "", map[string]string{"BRAID_ELECTOR_REFRESH_TICK": "5"}, "BRAID_ELECTOR_REFRESH_TICK"},
+		{"env ports", "a.yaml", "", map[string]string{"BRAID_DISCOVER_PORTS": "base"}, "BRAID_DISCOVER_PORTS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := LoadDirectorConfig(writeConfig(t, tt.file, tt.content))
+			assert.NotNil(t, err)
+
+			if tt.key != "" {
+				var cerr *ConfigError
+				assert.True(t, errors.As(err, &cerr), err)
+				assert.Equal(t, tt.key, cerr.Key)
+			}
+		})
+	}
+
+	_, err := LoadDirectorConfig("braid.json")
+	assert.NotNil(t, err)
+}
+
+func TestDirectorConfigServerHandler(t *testing.T) {
+
+	// 空的配置文件使用默认配置
+	_, err := LoadDirectorConfig(writeConfig(t, "empty.yaml", ""))
+	assert.Nil(t, err)
+
+	// 只配置了 server.listen，没有在代码中设置 handler
+	opts, err := LoadDirectorOpts(writeConfig(t, "braid.yaml", "server:\n  listen: :14222\n"))
+	assert.Nil(t, err)
+
+	d := NewStandaloneDirector(opts)
+
+	var cerr *ConfigError
+	err = d.Build()
+	assert.True(t, errors.As(err, &cerr), err)
+	assert.Equal(t, "server.listen", cerr.Key)
+}
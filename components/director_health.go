@@ -0,0 +1,99 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// DefaultHealthTimeout 没有设置 DirectorOpts.HealthTimeout 时，http 健康检查使用的超时时间
+const DefaultHealthTimeout = time.Second * 3
+
+// Health 获取所有模块的健康状态
+func (d *DefaultDirector) Health(ctx context.Context) HealthReport {
+	return d.lifecycle.Health(ctx)
+}
+
+// HealthHandler 获取提供给 k8s 探针使用的 http handler（见 NewHealthHandler
+func (d *DefaultDirector) HealthHandler() http.Handler {
+	timeout := d.Opts.HealthTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+
+	return NewHealthHandler(d.Health, timeout)
+}
+
+// NewHealthHandler 创建健康检查的 http handler，返回 json 格式的 HealthReport
+//
+//	/healthz liveness  所有模块存活时返回 200，否则返回 503
+//	/readyz  readiness 所有模块就绪时返回 200，否则返回 503
+//	/health  同 readiness
+func NewHealthHandler(check func(ctx context.Context) HealthReport, timeout time.Duration) http.Handler {
+
+	probe := func(ok func(HealthReport) bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			report := check(ctx)
+
+			w.Header().Set("Content-Type", "application/json")
+			if ok(report) {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+
+			json.NewEncoder(w).Encode(report)
+		}
+	}
+
+	live := probe(func(report HealthReport) bool { return report.Live })
+	ready := probe(func(report HealthReport) bool { return report.Ready })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", live)
+	mux.HandleFunc("/readyz", ready)
+	mux.HandleFunc("/health", ready)
+
+	return mux
+}
+
+// healthComponent 在 DirectorOpts.HealthAddr 上提供健康检查的 http 服务
+//
+//	最先初始化，最后关闭；在其他模块初始化期间 liveness 也可以被访问
+func (d *DefaultDirector) healthComponent() LifecycleComponent {
+
+	srv := &http.Server{
+		Addr:    d.Opts.HealthAddr,
+		Handler: d.HealthHandler(),
+	}
+
+	return LifecycleComponent{
+		Name: meta.ModuleHealth,
+		Init: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			d.log.Infof("[braid.health] listen %v", srv.Addr)
+
+			go func() {
+				if err := srv.Serve(lis); err != nil && err != http.ErrServerClosed {
+					d.log.Errf("[braid.health] serve err %v", err.Error())
+				}
+			}()
+
+			return nil
+		},
+		Close: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	}
+}
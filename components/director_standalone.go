@@ -0,0 +1,70 @@
+package components
+
+import (
+	"github.com/pojol/braid-go/components/discovermemory"
+	"github.com/pojol/braid-go/components/electormemory"
+	"github.com/pojol/braid-go/components/linkcachememory"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+)
+
+// Standalone 基于进程内存实现的模块之间共享的状态（topic、服务注册表、选举锁以及链路信息
+//
+// 使用同一个 Standalone 的 director 之间可以互相发现、选举以及调用，不同的 Standalone 之间相互隔离
+type Standalone struct {
+	Broker   *pubsubmemory.Broker
+	Registry *discovermemory.Registry
+	Leases   *electormemory.Leases
+	Store    *linkcachememory.Store
+}
+
+// NewStandalone 创建一组新的共享状态
+func NewStandalone() *Standalone {
+	return &Standalone{
+		Broker:   pubsubmemory.NewBroker(),
+		Registry: discovermemory.NewRegistry(),
+		Leases:   electormemory.NewLeases(),
+		Store:    linkcachememory.NewStore(),
+	}
+}
+
+// GlobalStandalone 进程内全局共享的状态（需要通过 DirectorOpts.Standalone 显式使用
+func GlobalStandalone() *Standalone {
+	return &Standalone{
+		Broker:   pubsubmemory.GlobalBroker(),
+		Registry: discovermemory.GlobalRegistry(),
+		Leases:   electormemory.GlobalLeases(),
+		Store:    linkcachememory.GlobalStore(),
+	}
+}
+
+// NewStandaloneDirector 构建一个所有模块都基于进程内存实现的 director
+//
+// 不依赖 redis, k8s, consul 等外部服务，使用同一个 DirectorOpts.Standalone 的多个服务可以互相发现、选举以及调用
+// 主要用于单进程部署、本地开发以及集成测试
+func NewStandaloneDirector(opts *DirectorOpts) *DefaultDirector {
+
+	if opts == nil {
+		opts = &DirectorOpts{}
+	}
+
+	opts.Pubsub = PubsubMemory
+	opts.Discover = DiscoverMemory
+	opts.Elector = ElectorMemory
+	opts.Linkcache = LinkcacheMemory
+	opts.Monitor = None
+
+	return &DefaultDirector{
+		Opts: opts,
+	}
+}
+
+// standalone 内存模块共享的状态，没有设置 DirectorOpts.Standalone 时每个 director 单独创建
+func (d *DefaultDirector) standalone() *Standalone {
+	if d.memory == nil {
+		d.memory = d.Opts.Standalone
+		if d.memory == nil {
+			d.memory = NewStandalone()
+		}
+	}
+	return d.memory
+}
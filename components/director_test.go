@@ -0,0 +1,139 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubPubsub struct{}
+
+func (ps *stubPubsub) GetTopic(name string) module.ITopic { return nil }
+func (ps *stubPubsub) Info()                              {}
+
+func TestSelectImplementation(t *testing.T) {
+
+	d := &DefaultDirector{
+		Opts: &DirectorOpts{
+			LogOpts: []blog.Option{blog.WithLevel(int(blog.DebugLevel))},
+			PubsubFactory: func(d *DefaultDirector) (module.IPubsub, error) {
+				assert.Equal(t, d.ServiceInfo().Name, "test_select")
+				return pubsubmemory.BuildWithOption(d.ServiceInfo(), d.Logger()), nil
+			},
+			Discover:  None,
+			Elector:   None,
+			Linkcache: None,
+			Monitor:   None,
+		},
+	}
+	d.SetServiceInfo(meta.ServiceInfo{ID: "id", Name: "test_select"})
+
+	err := d.Build()
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, d.Pubsub(), nil)
+	assert.NotEqual(t, d.Client(), nil)
+	assert.Equal(t, d.discovery, nil)
+	assert.Equal(t, d.elector, nil)
+	assert.Equal(t, d.linkcache, nil)
+	assert.Equal(t, d.monitor, nil)
+	// 没有使用到的依赖不应该被创建
+	assert.Nil(t, d.rediscli)
+	assert.Nil(t, d.k8scli)
+
+	order, err := d.Lifecycle().Order()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, order, []string{meta.ModuleDepends, meta.ModulePubsub, meta.ModuleBalancer, meta.ModuleClient})
+
+	assert.Equal(t, d.Init(context.TODO()), nil)
+	assert.Equal(t, d.Run(context.TODO()), nil)
+	assert.Equal(t, d.Close(context.TODO()), nil)
+}
+
+func TestSelectUnknown(t *testing.T) {
+
+	d := &DefaultDirector{
+		Opts: &DirectorOpts{
+			Pubsub: None,
+		},
+	}
+	assert.NotEqual(t, d.Build(), nil)
+
+	d = &DefaultDirector{
+		Opts: &DirectorOpts{
+			PubsubFactory: func(d *DefaultDirector) (module.IPubsub, error) {
+				return &stubPubsub{}, nil
+			},
+			Discover: "unknown",
+		},
+	}
+	assert.NotEqual(t, d.Build(), nil)
+}
+
+func TestHealthHandler(t *testing.T) {
+
+	d := NewStandaloneDirector(&DirectorOpts{
+		Elector:   None,
+		Linkcache: None,
+	})
+	d.SetServiceInfo(meta.ServiceInfo{ID: "id", Name: "test_health"})
+	assert.Equal(t, d.Build(), nil)
+
+	srv := httptest.NewServer(d.HealthHandler())
+	defer srv.Close()
+
+	probe := func(path string) (int, HealthReport) {
+		res, err := http.Get(srv.URL + path)
+		assert.Equal(t, err, nil)
+		defer res.Body.Close()
+
+		report := HealthReport{}
+		assert.Equal(t, json.NewDecoder(res.Body).Decode(&report), nil)
+		return res.StatusCode, report
+	}
+
+	assert.Equal(t, d.Init(context.TODO()), nil)
+
+	code, report := probe("/healthz")
+	assert.Equal(t, code, http.StatusOK)
+	assert.True(t, report.Live)
+
+	code, _ = probe("/readyz")
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+
+	assert.Equal(t, d.Run(context.TODO()), nil)
+	defer d.Close(context.TODO())
+
+	code, report = probe("/readyz")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Contains(t, report.Components, meta.ModuleDiscover)
+}
+
+func TestStandaloneState(t *testing.T) {
+
+	shared := NewStandalone()
+
+	build := func(id string, s *Standalone) *DefaultDirector {
+		d := NewStandaloneDirector(&DirectorOpts{Standalone: s})
+		d.SetServiceInfo(meta.ServiceInfo{ID: id, Name: "test_standalone"})
+		assert.Equal(t, d.Build(), nil)
+		return d
+	}
+
+	d1 := build("1", shared)
+	d2 := build("2", shared)
+	d3 := build("3", nil)
+
+	// 没有指定 Standalone 的 director 相互隔离，全局的状态需要显式使用
+	assert.Same(t, d1.standalone(), d2.standalone())
+	assert.NotSame(t, d1.standalone().Broker, d3.standalone().Broker)
+	assert.NotSame(t, d3.standalone().Registry, GlobalStandalone().Registry)
+	assert.Same(t, GlobalStandalone().Registry, GlobalStandalone().Registry)
+}
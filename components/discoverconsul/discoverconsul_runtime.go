@@ -4,6 +4,7 @@ package discoverconsul
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -20,6 +21,9 @@ const (
 	// DiscoverTag 用于docker发现的tag， 所有希望被discover服务发现的节点，
 	// 都应该在Dockerfile中设置 ENV SERVICE_TAGS=braid
 	DiscoverTag = "braid"
+
+	// WeightMetaKey 节点权重在 consul 元数据中的键（与 grpcserver.RegisterWeightKey 一致
+	WeightMetaKey = "weight"
 )
 
 var (
@@ -69,6 +73,11 @@ type consulDiscover struct {
 	// service id : service nod
 	nodemap map[string]*meta.Node
 
+	// 最后一次成功获取服务列表的时间
+	syncAt time.Time
+	// 最后一次获取服务列表的错误
+	syncErr error
+
 	lock sync.Mutex
 }
 
@@ -118,10 +127,12 @@ func (dc *consulDiscover) discoverImpl() {
 	servicesnodes := make(map[string]bool)
 
 	services, err := dc.client.CatalogListServices()
+	dc.syncErr = err
 	if err != nil {
 		dc.log.Warnf("[braid.discover] discover impl err %v", err.Error())
 		return
 	}
+	dc.syncAt = time.Now()
 
 	for _, v := range services {
 		cs, err := dc.client.CatalogGetService(v.Info.Name)
@@ -157,11 +168,13 @@ func (dc *consulDiscover) discoverImpl() {
 			if _, ok := dc.nodemap[nod.ID]; !ok {
 
 				sn := meta.Node{
-					Name:    v.Info.Name,
-					ID:      nod.ID,
-					Address: nod.Address + ":" + strconv.Itoa(nod.Port),
+					Name:     v.Info.Name,
+					ID:       nod.ID,
+					Address:  nod.Address + ":" + strconv.Itoa(nod.Port),
+					Weight:   nodeWeight(nod.Metadata),
+					Metadata: nod.Metadata,
 				}
-				dc.log.Infof("[braid.discover] new service %s node %s addr %s", v.Info.Name, nod.ID, sn.Address)
+				dc.log.Infof("[braid.discover] new service %s node %s addr %s weight %d", v.Info.Name, nod.ID, sn.Address, sn.Weight)
 				dc.nodemap[nod.ID] = &sn
 
 				dc.ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(),
@@ -194,6 +207,21 @@ func (dc *consulDiscover) discoverImpl() {
 
 }
 
+// nodeWeight 从注册的元数据中获取节点的初始权重（没有设置或者不合法时为 0
+func nodeWeight(md map[string]interface{}) int {
+	v, ok := md[WeightMetaKey].(string)
+	if !ok {
+		return 0
+	}
+
+	weight, err := strconv.Atoi(v)
+	if err != nil || weight < 0 {
+		return 0
+	}
+
+	return weight
+}
+
 func (dc *consulDiscover) syncWeight() {
 	dc.lock.Lock()
 	defer dc.lock.Unlock()
@@ -279,6 +307,26 @@ func (dc *consulDiscover) Run() {
 	}()
 }
 
+// Health 最近的同步周期内（3 个同步间隔）成功获取过服务列表时就绪
+func (dc *consulDiscover) Health(ctx context.Context) module.HealthStatus {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	if dc.syncAt.IsZero() {
+		return module.HealthStatus{Live: true, Message: "services not synced"}
+	}
+
+	if time.Since(dc.syncAt) > dc.parm.SyncServicesInterval*3 {
+		msg := fmt.Sprintf("services last synced %v ago", time.Since(dc.syncAt).Truncate(time.Millisecond))
+		if dc.syncErr != nil {
+			msg += " err " + dc.syncErr.Error()
+		}
+		return module.HealthStatus{Live: true, Message: msg}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true}
+}
+
 // Close close
 func (dc *consulDiscover) Close() {
 
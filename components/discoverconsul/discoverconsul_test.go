@@ -11,6 +11,7 @@ import (
 	"github.com/pojol/braid-go/mock"
 	"github.com/pojol/braid-go/module/meta"
 	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestMain(m *testing.M) {
@@ -53,3 +54,21 @@ func TestDiscover(t *testing.T) {
 	dc.Close()
 
 }
+
+func TestNodeWeight(t *testing.T) {
+
+	tests := []struct {
+		md     map[string]interface{}
+		weight int
+	}{
+		{nil, 0},
+		{map[string]interface{}{WeightMetaKey: "100"}, 100},
+		{map[string]interface{}{WeightMetaKey: "-1"}, 0},
+		{map[string]interface{}{WeightMetaKey: "abc"}, 0},
+		{map[string]interface{}{"other": "100"}, 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, nodeWeight(tt.md), tt.weight)
+	}
+}
@@ -2,6 +2,7 @@ package discoverk8s
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -26,6 +27,11 @@ type k8sDiscover struct {
 	// service id : service nod
 	nodemap map[string]*meta.Node
 
+	// 最后一次成功获取服务列表的时间
+	syncAt time.Time
+	// 最后一次获取服务列表的错误
+	syncErr error
+
 	sync.Mutex
 }
 
@@ -66,10 +72,12 @@ func (k *k8sDiscover) discoverImpl() {
 	updateflag := false
 
 	services, err := k.cli.ListServices(context.TODO(), k.parm.Namespace)
+	k.syncErr = err
 	if err != nil {
 		k.log.Warnf("[braid.discover] err %v", err.Error())
 		return
 	}
+	k.syncAt = time.Now()
 
 	for _, v := range services {
 		if v.Info.Name == "" || len(v.Nodes) == 0 {
@@ -173,6 +181,26 @@ func (k *k8sDiscover) Run() {
 	}()
 }
 
+// Health 最近的同步周期内（3 个同步间隔）成功获取过服务列表时就绪
+func (k *k8sDiscover) Health(ctx context.Context) module.HealthStatus {
+	k.Lock()
+	defer k.Unlock()
+
+	if k.syncAt.IsZero() {
+		return module.HealthStatus{Live: true, Message: "services not synced"}
+	}
+
+	if time.Since(k.syncAt) > k.parm.SyncServicesInterval*3 {
+		msg := fmt.Sprintf("services last synced %v ago", time.Since(k.syncAt).Truncate(time.Millisecond))
+		if k.syncErr != nil {
+			msg += " err " + k.syncErr.Error()
+		}
+		return module.HealthStatus{Live: true, Message: msg}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true}
+}
+
 func (k *k8sDiscover) Close() {
 
 }
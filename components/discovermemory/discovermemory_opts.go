@@ -0,0 +1,71 @@
+package discovermemory
+
+import (
+	"time"
+)
+
+// Parm discover config
+type Parm struct {
+	// 同步节点信息间隔
+	SyncServicesInterval time.Duration
+
+	// 自身注册到进程中的地址，为空时不注册（只作为调用方
+	Address string
+
+	// 自身注册的标签
+	Tags []string
+
+	// 过滤标签
+	Tag string
+
+	// 黑名单列表，在黑名单的服务将不会被加入到 services 中
+	Blacklist []string
+
+	// 共享的服务注册表，为空时每个实例单独创建
+	Registry *Registry
+}
+
+// Option memory discover config wrapper
+type Option func(*Parm)
+
+// WithSyncServiceInterval 修改config中的interval
+func WithSyncServiceInterval(interval time.Duration) Option {
+	return func(c *Parm) {
+		c.SyncServicesInterval = interval
+	}
+}
+
+// WithAddress 设置自身注册的地址（通常是 rpc-server 的侦听地址
+func WithAddress(addr string) Option {
+	return func(c *Parm) {
+		c.Address = addr
+	}
+}
+
+// WithTags 设置自身注册的标签
+func WithTags(tags []string) Option {
+	return func(c *Parm) {
+		c.Tags = tags
+	}
+}
+
+// WithTag 修改config中的discover tag
+func WithTag(discoverTag string) Option {
+	return func(c *Parm) {
+		c.Tag = discoverTag
+	}
+}
+
+// WithRegistry 设置共享的服务注册表（NewRegistry or GlobalRegistry
+func WithRegistry(r *Registry) Option {
+	return func(c *Parm) {
+		c.Registry = r
+	}
+}
+
+// WithBlacklist add blacklist
+func WithBlacklist(lst []string) Option {
+	return func(c *Parm) {
+		c.Blacklist = lst
+	}
+}
@@ -0,0 +1,222 @@
+// 实现文件 基于进程内存实现的服务发现，使用同一个 Registry 的服务可以互相发现
+package discovermemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/internal/utils"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+const (
+	// DiscoverTag 默认的注册 & 过滤标签
+	DiscoverTag = "braid"
+)
+
+// Registry 服务注册表，使用同一个 Registry 的实例可以互相发现
+type Registry struct {
+	sync.RWMutex
+
+	// service id : service
+	services map[string]meta.Service
+}
+
+// NewRegistry 创建一个服务注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		services: make(map[string]meta.Service),
+	}
+}
+
+var globalRegistry = NewRegistry()
+
+// GlobalRegistry 进程内全局共享的服务注册表（需要通过 WithRegistry 显式使用
+func GlobalRegistry() *Registry {
+	return globalRegistry
+}
+
+func (r *Registry) register(info meta.ServiceInfo, nod meta.Node, tags []string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.services[info.ID] = meta.Service{
+		Info:  info,
+		Nodes: []meta.Node{nod},
+		Tags:  tags,
+	}
+}
+
+func (r *Registry) deregister(id string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.services, id)
+}
+
+func (r *Registry) list() []meta.Service {
+	r.RLock()
+	defer r.RUnlock()
+
+	services := make([]meta.Service, 0, len(r.services))
+	for _, v := range r.services {
+		services = append(services, v)
+	}
+
+	return services
+}
+
+type memoryDiscover struct {
+	info meta.ServiceInfo
+	parm Parm
+
+	log *blog.Logger
+	ps  module.IPubsub
+	r   *Registry
+
+	discoverTicker *time.Ticker
+	done           chan struct{}
+
+	// service id : service nod
+	nodemap map[string]*meta.Node
+
+	sync.Mutex
+}
+
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, ps module.IPubsub, opts ...Option) module.IDiscover {
+
+	p := Parm{
+		SyncServicesInterval: time.Millisecond * 500,
+		Tag:                  DiscoverTag,
+		Tags:                 []string{DiscoverTag},
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if p.Registry == nil {
+		p.Registry = NewRegistry()
+	}
+
+	return &memoryDiscover{
+		info:    info,
+		parm:    p,
+		log:     log,
+		ps:      ps,
+		r:       p.Registry,
+		done:    make(chan struct{}),
+		nodemap: make(map[string]*meta.Node),
+	}
+}
+
+func (md *memoryDiscover) Init() error {
+	return nil
+}
+
+func (md *memoryDiscover) discoverImpl() {
+
+	md.Lock()
+	defer md.Unlock()
+
+	servicesnodes := make(map[string]bool)
+
+	for _, v := range md.r.list() {
+
+		if !utils.ContainsInSlice(v.Tags, md.parm.Tag) {
+			continue
+		}
+
+		if v.Info.Name == md.info.Name {
+			continue
+		}
+
+		if utils.ContainsInSlice(md.parm.Blacklist, v.Info.Name) {
+			continue // 排除黑名单节点
+		}
+
+		// 添加节点
+		for _, nod := range v.Nodes {
+
+			servicesnodes[nod.ID] = true
+
+			if _, ok := md.nodemap[nod.ID]; !ok {
+
+				sn := nod
+				md.log.Infof("[braid.discover] new service %s node %s addr %s", v.Info.Name, nod.ID, sn.Address)
+				md.nodemap[nod.ID] = &sn
+
+				md.ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(), meta.EncodeUpdateMsg(
+					meta.TopicDiscoverServiceNodeAdd,
+					sn,
+				))
+			}
+		}
+	}
+
+	// 排除节点
+	for k := range md.nodemap {
+
+		if _, ok := servicesnodes[k]; !ok {
+			md.log.Infof("[braid.discover] remove service %s node %s", md.nodemap[k].Name, md.nodemap[k].ID)
+
+			md.ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(), meta.EncodeUpdateMsg(
+				meta.TopicDiscoverServiceNodeRmv,
+				*md.nodemap[k],
+			))
+
+			delete(md.nodemap, k)
+		}
+	}
+}
+
+func (md *memoryDiscover) discover() {
+	syncService := func() {
+		defer func() {
+			if err := recover(); err != nil {
+				md.log.Errf("[braid.discover] syncService err %v", err)
+			}
+		}()
+
+		md.discoverImpl()
+	}
+
+	md.discoverTicker = time.NewTicker(md.parm.SyncServicesInterval)
+	defer md.discoverTicker.Stop()
+
+	md.discoverImpl()
+
+	for {
+		select {
+		case <-md.discoverTicker.C:
+			syncService()
+		case <-md.done:
+			return
+		}
+	}
+}
+
+// Run 将自身注册到进程中，并开始同步其他服务的节点信息
+func (md *memoryDiscover) Run() {
+
+	if md.parm.Address != "" {
+		md.r.register(md.info, meta.Node{
+			ID:      md.info.ID,
+			Name:    md.info.Name,
+			Address: md.parm.Address,
+		}, md.parm.Tags)
+	}
+
+	go func() {
+		md.discover()
+	}()
+}
+
+// Close 将自身从进程中注销
+func (md *memoryDiscover) Close() {
+	md.r.deregister(md.info.ID)
+	close(md.done)
+}
@@ -0,0 +1,84 @@
+package discovermemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscover(t *testing.T) {
+
+	log := blog.BuildWithOption()
+	ps := pubsubmemory.BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, log)
+
+	events := make(chan meta.UpdateMsg, 10)
+	channel, _ := ps.GetTopic(meta.TopicDiscoverServiceUpdate).Sub(context.TODO(), "test-discover")
+	defer channel.Close()
+	channel.Arrived(func(msg *meta.Message) error {
+		dmsg := meta.DecodeUpdateMsg(msg)
+		if dmsg.Nod.Name == "mem-base" {
+			events <- dmsg
+		}
+		return nil
+	})
+
+	r := NewRegistry()
+	base := BuildWithOption(
+		meta.ServiceInfo{ID: "base-1", Name: "mem-base"},
+		log,
+		ps,
+		WithAddress("127.0.0.1:14001"),
+		WithSyncServiceInterval(time.Millisecond*10),
+		WithRegistry(r),
+	)
+	gate := BuildWithOption(
+		meta.ServiceInfo{ID: "gate-1", Name: "mem-gate"},
+		log,
+		ps,
+		WithSyncServiceInterval(time.Millisecond*10),
+		WithRegistry(r),
+		WithBlacklist([]string{"mem-login"}),
+	)
+	login := BuildWithOption(
+		meta.ServiceInfo{ID: "login-1", Name: "mem-login"},
+		log,
+		ps,
+		WithAddress("127.0.0.1:14002"),
+		WithSyncServiceInterval(time.Millisecond*10),
+		WithRegistry(r),
+	)
+
+	base.Run()
+	login.Run()
+	gate.Run()
+	defer gate.Close()
+	defer login.Close()
+
+	select {
+	case dmsg := <-events:
+		assert.Equal(t, dmsg.Event, meta.TopicDiscoverServiceNodeAdd)
+		assert.Equal(t, dmsg.Nod.ID, "base-1")
+		assert.Equal(t, dmsg.Nod.Address, "127.0.0.1:14001")
+	case <-time.After(time.Second):
+		t.Fatal("node add not discovered")
+	}
+
+	base.Close()
+
+	for {
+		select {
+		case dmsg := <-events:
+			if dmsg.Event == meta.TopicDiscoverServiceNodeRmv {
+				assert.Equal(t, dmsg.Nod.ID, "base-1")
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("node rmv not discovered")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package discoverstatic
+
+import (
+	"time"
+
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// Parm discover config
+type Parm struct {
+	// 检查文件变更的间隔
+	SyncServicesInterval time.Duration
+
+	// 服务节点描述文件（yaml / json
+	//
+	//	services:
+	//	  - name: base
+	//	    nodes:
+	//	      - id: base-1
+	//	        address: 127.0.0.1:14001
+	//	        metadata:
+	//	          weight: 100
+	Path string
+
+	// 在代码中直接设置的节点列表（Node.Name 为服务名），会和文件中的节点合并
+	Nodes []meta.Node
+
+	// 黑名单列表，在黑名单的服务将不会被加入到 services 中
+	Blacklist []string
+}
+
+// Option static discover config wrapper
+type Option func(*Parm)
+
+// WithSyncServiceInterval 修改检查文件变更的间隔
+func WithSyncServiceInterval(interval time.Duration) Option {
+	return func(c *Parm) {
+		c.SyncServicesInterval = interval
+	}
+}
+
+// WithFile 设置服务节点描述文件
+func WithFile(path string) Option {
+	return func(c *Parm) {
+		c.Path = path
+	}
+}
+
+// WithNodes 设置静态的节点列表
+func WithNodes(nodes []meta.Node) Option {
+	return func(c *Parm) {
+		c.Nodes = nodes
+	}
+}
+
+// WithBlacklist add blacklist
+func WithBlacklist(lst []string) Option {
+	return func(c *Parm) {
+		c.Blacklist = lst
+	}
+}
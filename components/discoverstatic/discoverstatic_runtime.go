@@ -0,0 +1,289 @@
+// 实现文件 基于静态配置（代码 or 文件）实现的服务发现
+package discoverstatic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/internal/utils"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"gopkg.in/yaml.v3"
+)
+
+type fileNode struct {
+	ID       string                 `yaml:"id"`
+	Address  string                 `yaml:"address"`
+	Metadata map[string]interface{} `yaml:"metadata"`
+}
+
+type fileService struct {
+	Name  string     `yaml:"name"`
+	Nodes []fileNode `yaml:"nodes"`
+}
+
+type fileConfig struct {
+	Services []fileService `yaml:"services"`
+}
+
+type staticDiscover struct {
+	info meta.ServiceInfo
+	parm Parm
+
+	log *blog.Logger
+	ps  module.IPubsub
+
+	discoverTicker *time.Ticker
+	done           chan struct{}
+
+	// 文件的最后修改时间，用于判断文件是否发生了变更
+	modTime time.Time
+	size    int64
+	// 最后一次成功加载的文件节点
+	fileNodes []meta.Node
+	// 最后一次加载文件的错误
+	loadErr error
+	synced  bool
+
+	// service id : service nod
+	nodemap map[string]*meta.Node
+
+	sync.Mutex
+}
+
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, ps module.IPubsub, opts ...Option) module.IDiscover {
+
+	p := Parm{
+		SyncServicesInterval: time.Second * 2,
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return &staticDiscover{
+		info:    info,
+		parm:    p,
+		log:     log,
+		ps:      ps,
+		done:    make(chan struct{}),
+		nodemap: make(map[string]*meta.Node),
+	}
+}
+
+// Init 检查节点描述文件是否可以被正确的解析
+func (sd *staticDiscover) Init() error {
+
+	if sd.parm.Path == "" {
+		return nil
+	}
+
+	_, err := sd.load()
+	if err != nil {
+		return fmt.Errorf("[braid.discover] load %v err %w", sd.parm.Path, err)
+	}
+
+	return nil
+}
+
+// loadFile 解析节点描述文件（json 是 yaml 的子集，因此统一使用 yaml 解析
+//
+//	空文件 or 没有 services 字段的文件（如正在写入的文件）视为错误，需要移除所有节点时使用 services: []
+func loadFile(path string) ([]meta.Node, error) {
+
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := fileConfig{}
+	err = yaml.Unmarshal(byt, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Services == nil {
+		return nil, fmt.Errorf("services not found")
+	}
+
+	nodes := []meta.Node{}
+	for _, service := range cfg.Services {
+		if service.Name == "" {
+			return nil, fmt.Errorf("service name is empty")
+		}
+
+		for _, nod := range service.Nodes {
+			if nod.ID == "" || nod.Address == "" {
+				return nil, fmt.Errorf("service %v node id or address is empty", service.Name)
+			}
+
+			nodes = append(nodes, meta.Node{
+				ID:       nod.ID,
+				Name:     service.Name,
+				Address:  nod.Address,
+				Metadata: nod.Metadata,
+			})
+		}
+	}
+
+	return nodes, nil
+}
+
+// load 当文件发生变更时重新加载，加载失败时沿用上一次成功加载的节点
+func (sd *staticDiscover) load() ([]meta.Node, error) {
+
+	fi, err := os.Stat(sd.parm.Path)
+	if err != nil {
+		return sd.fileNodes, err
+	}
+
+	if fi.ModTime().Equal(sd.modTime) && fi.Size() == sd.size {
+		return sd.fileNodes, nil
+	}
+
+	nodes, err := loadFile(sd.parm.Path)
+	if err != nil {
+		return sd.fileNodes, err
+	}
+
+	// 读取的过程中文件发生了变更（文件正在被写入），等待下一次同步时重新加载
+	after, err := os.Stat(sd.parm.Path)
+	if err != nil {
+		return sd.fileNodes, err
+	}
+	if !after.ModTime().Equal(fi.ModTime()) || after.Size() != fi.Size() {
+		return sd.fileNodes, fmt.Errorf("file changed while loading")
+	}
+
+	sd.modTime = fi.ModTime()
+	sd.size = fi.Size()
+	sd.fileNodes = nodes
+	sd.log.Infof("[braid.discover] load %v nodes %d", sd.parm.Path, len(nodes))
+
+	return nodes, nil
+}
+
+func (sd *staticDiscover) pub(event string, nod meta.Node) {
+	sd.ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(), meta.EncodeUpdateMsg(
+		event,
+		nod,
+	))
+}
+
+func (sd *staticDiscover) discoverImpl() {
+
+	sd.Lock()
+	defer sd.Unlock()
+
+	nodes := append([]meta.Node{}, sd.parm.Nodes...)
+
+	if sd.parm.Path != "" {
+		fnodes, err := sd.load()
+		sd.loadErr = err
+		if err != nil {
+			sd.log.Warnf("[braid.discover] load %v err %v", sd.parm.Path, err.Error())
+		}
+		nodes = append(nodes, fnodes...)
+	}
+
+	servicesnodes := make(map[string]bool)
+
+	for _, nod := range nodes {
+
+		if nod.Name == sd.info.Name {
+			continue
+		}
+
+		if utils.ContainsInSlice(sd.parm.Blacklist, nod.Name) {
+			continue // 排除黑名单节点
+		}
+
+		servicesnodes[nod.ID] = true
+		sn := nod
+
+		old, ok := sd.nodemap[nod.ID]
+		if !ok {
+			sd.log.Infof("[braid.discover] new service %s node %s addr %s", sn.Name, sn.ID, sn.Address)
+			sd.nodemap[nod.ID] = &sn
+			sd.pub(meta.TopicDiscoverServiceNodeAdd, sn)
+		} else if old.Address != sn.Address || old.Name != sn.Name {
+			// 地址变更，视为旧节点退出新节点加入
+			sd.log.Infof("[braid.discover] replace service %s node %s addr %s => %s", sn.Name, sn.ID, old.Address, sn.Address)
+			sd.pub(meta.TopicDiscoverServiceNodeRmv, *old)
+			sd.nodemap[nod.ID] = &sn
+			sd.pub(meta.TopicDiscoverServiceNodeAdd, sn)
+		} else if !reflect.DeepEqual(old.Metadata, sn.Metadata) {
+			sd.log.Infof("[braid.discover] update service %s node %s", sn.Name, sn.ID)
+			sd.nodemap[nod.ID] = &sn
+			sd.pub(meta.TopicDiscoverServiceNodeUpdate, sn)
+		}
+	}
+
+	sd.synced = true
+
+	// 排除节点
+	for k := range sd.nodemap {
+		if _, ok := servicesnodes[k]; !ok {
+			sd.log.Infof("[braid.discover] remove service %s node %s", sd.nodemap[k].Name, sd.nodemap[k].ID)
+			sd.pub(meta.TopicDiscoverServiceNodeRmv, *sd.nodemap[k])
+			delete(sd.nodemap, k)
+		}
+	}
+}
+
+// Health 完成第一次同步后就绪；文件加载失败时沿用上一次的节点，只在状态描述中给出错误
+func (sd *staticDiscover) Health(ctx context.Context) module.HealthStatus {
+	sd.Lock()
+	defer sd.Unlock()
+
+	if !sd.synced {
+		return module.HealthStatus{Live: true, Message: "services not synced"}
+	}
+
+	if sd.loadErr != nil {
+		return module.HealthStatus{Live: true, Ready: true, Message: "load file err " + sd.loadErr.Error()}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true}
+}
+
+func (sd *staticDiscover) discover() {
+	syncService := func() {
+		defer func() {
+			if err := recover(); err != nil {
+				sd.log.Errf("[braid.discover] syncService err %v", err)
+			}
+		}()
+
+		sd.discoverImpl()
+	}
+
+	sd.discoverTicker = time.NewTicker(sd.parm.SyncServicesInterval)
+	defer sd.discoverTicker.Stop()
+
+	syncService()
+
+	for {
+		select {
+		case <-sd.discoverTicker.C:
+			syncService()
+		case <-sd.done:
+			return
+		}
+	}
+}
+
+func (sd *staticDiscover) Run() {
+	go func() {
+		sd.discover()
+	}()
+}
+
+func (sd *staticDiscover) Close() {
+	close(sd.done)
+}
@@ -0,0 +1,182 @@
+package discoverstatic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+const staticV1 = `
+services:
+  - name: static-base
+    nodes:
+      - id: base-1
+        address: 127.0.0.1:14001
+      - id: base-2
+        address: 127.0.0.1:14002
+  - name: static-login
+    nodes:
+      - id: login-1
+        address: 127.0.0.1:14003
+`
+
+const staticV2 = `
+services:
+  - name: static-base
+    nodes:
+      - id: base-1
+        address: 127.0.0.1:14011
+      - id: base-2
+        address: 127.0.0.1:14002
+        metadata:
+          weight: 200
+`
+
+// writeFile 先写入临时文件再重命名，避免同步时读取到写入了一半的文件
+func writeFile(t *testing.T, path string, content string, mod time.Time) {
+	tmp := path + ".tmp"
+	assert.Nil(t, os.WriteFile(tmp, []byte(content), 0644))
+	assert.Nil(t, os.Chtimes(tmp, mod, mod))
+	assert.Nil(t, os.Rename(tmp, path))
+}
+
+func waitEvent(t *testing.T, events chan meta.UpdateMsg) meta.UpdateMsg {
+	select {
+	case dmsg := <-events:
+		return dmsg
+	case <-time.After(time.Second):
+		t.Fatal("event not arrived")
+	}
+	return meta.UpdateMsg{}
+}
+
+func TestStaticDiscover(t *testing.T) {
+
+	log := blog.BuildWithOption()
+	ps := pubsubmemory.BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, log)
+
+	events := make(chan meta.UpdateMsg, 10)
+	channel, _ := ps.GetTopic(meta.TopicDiscoverServiceUpdate).Sub(context.TODO(), "test-static-discover")
+	defer channel.Close()
+	channel.Arrived(func(msg *meta.Message) error {
+		events <- meta.DecodeUpdateMsg(msg)
+		return nil
+	})
+
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	now := time.Now()
+	writeFile(t, path, staticV1, now)
+
+	d := BuildWithOption(
+		meta.ServiceInfo{ID: "gate-1", Name: "static-gate"},
+		log,
+		ps,
+		WithFile(path),
+		WithSyncServiceInterval(time.Millisecond*10),
+		WithBlacklist([]string{"static-login"}),
+		WithNodes([]meta.Node{
+			{ID: "gate-1", Name: "static-gate", Address: "127.0.0.1:14000"},
+		}),
+	)
+	assert.Nil(t, d.Init())
+	assert.False(t, d.(module.IHealth).Health(context.TODO()).Ready)
+	d.Run()
+	defer d.Close()
+
+	added := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		dmsg := waitEvent(t, events)
+		assert.Equal(t, dmsg.Event, meta.TopicDiscoverServiceNodeAdd)
+		added[dmsg.Nod.ID] = dmsg.Nod.Address
+	}
+	assert.Equal(t, added, map[string]string{
+		"base-1": "127.0.0.1:14001",
+		"base-2": "127.0.0.1:14002",
+	})
+
+	// 解析失败的文件不会影响已经加载的节点
+	writeFile(t, path, "services: [", now.Add(time.Second))
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, len(events), 0)
+	status := d.(module.IHealth).Health(context.TODO())
+	assert.True(t, status.Ready)
+	assert.NotEqual(t, status.Message, "")
+
+	// 空文件不会移除已经加载的节点
+	writeFile(t, path, "", now.Add(time.Second*2))
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, len(events), 0)
+	assert.NotEqual(t, d.(module.IHealth).Health(context.TODO()).Message, "")
+
+	writeFile(t, path, staticV2, now.Add(time.Second*3))
+
+	received := []meta.UpdateMsg{}
+	for i := 0; i < 3; i++ {
+		received = append(received, waitEvent(t, events))
+	}
+
+	var rmv, add, update bool
+	for _, dmsg := range received {
+		switch dmsg.Event {
+		case meta.TopicDiscoverServiceNodeRmv:
+			assert.Equal(t, dmsg.Nod.Address, "127.0.0.1:14001")
+			rmv = true
+		case meta.TopicDiscoverServiceNodeAdd:
+			assert.Equal(t, dmsg.Nod.Address, "127.0.0.1:14011")
+			add = true
+		case meta.TopicDiscoverServiceNodeUpdate:
+			assert.Equal(t, dmsg.Nod.ID, "base-2")
+			assert.Equal(t, dmsg.Nod.Metadata["weight"], float64(200))
+			update = true
+		}
+	}
+	assert.True(t, rmv && add && update)
+}
+
+func TestStaticDiscoverJSON(t *testing.T) {
+
+	log := blog.BuildWithOption()
+	ps := pubsubmemory.BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, log)
+
+	events := make(chan meta.UpdateMsg, 10)
+	channel, _ := ps.GetTopic(meta.TopicDiscoverServiceUpdate).Sub(context.TODO(), "test-static-discover-json")
+	defer channel.Close()
+	channel.Arrived(func(msg *meta.Message) error {
+		events <- meta.DecodeUpdateMsg(msg)
+		return nil
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "services.json")
+	writeFile(t, path, `{"services":[{"name":"json-base","nodes":[{"id":"json-1","address":"127.0.0.1:14021"}]}]}`, time.Now())
+
+	d := BuildWithOption(meta.ServiceInfo{ID: "gate-1", Name: "json-gate"}, log, ps,
+		WithFile(path),
+		WithSyncServiceInterval(time.Millisecond*10),
+	)
+	assert.Nil(t, d.Init())
+	d.Run()
+	defer d.Close()
+
+	dmsg := waitEvent(t, events)
+	assert.Equal(t, dmsg.Event, meta.TopicDiscoverServiceNodeAdd)
+	assert.Equal(t, dmsg.Nod.Name, "json-base")
+	assert.Equal(t, dmsg.Nod.Address, "127.0.0.1:14021")
+
+	// 无法解析的文件在 Init 阶段返回错误
+	bad := filepath.Join(dir, "bad.yaml")
+	writeFile(t, bad, "services:\n  - nodes:\n      - id: x\n", time.Now())
+	assert.NotNil(t, BuildWithOption(meta.ServiceInfo{ID: "gate-1", Name: "json-gate"}, log, ps, WithFile(bad)).Init())
+
+	empty := filepath.Join(dir, "empty.yaml")
+	writeFile(t, empty, "", time.Now())
+	assert.NotNil(t, BuildWithOption(meta.ServiceInfo{ID: "gate-1", Name: "json-gate"}, log, ps, WithFile(empty)).Init())
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/pojol/braid-go/components/depends/bconsul"
@@ -51,6 +52,7 @@ func BuildWithOption(info meta.ServiceInfo, opts ...Option) module.IElector {
 	p.Pubsub.GetTopic(info.Name + "." + info.ID + "." + meta.TopicElectionChangeState)
 
 	return &consulElection{
+		info:   info,
 		parm:   p,
 		ps:     p.Pubsub,
 		client: p.ConsulCli,
@@ -67,6 +69,8 @@ type consulElection struct {
 
 	sessionID string
 	locked    bool
+	// 获取锁 or 最后一次续租成功的时间
+	renewAt time.Time
 
 	log *blog.Logger
 	ps  module.IPubsub
@@ -74,6 +78,8 @@ type consulElection struct {
 	info meta.ServiceInfo
 
 	parm Parm
+
+	sync.Mutex
 }
 
 func (e *consulElection) watch() {
@@ -84,6 +90,9 @@ func (e *consulElection) watch() {
 			}
 		}()
 
+		e.Lock()
+		defer e.Unlock()
+
 		if !e.locked {
 			succ, err := e.client.AcquireLock(e.info.Name, e.sessionID)
 			if err != nil {
@@ -91,6 +100,7 @@ func (e *consulElection) watch() {
 			}
 			if succ {
 				e.locked = true
+				e.renewAt = time.Now()
 				e.ps.GetTopic(meta.TopicElectionChangeState).Pub(context.TODO(), meta.EncodeStateChangeMsg(meta.EMaster, e.info.ID))
 				e.log.Infof("[Elector] acquire lock service %s, id %s", e.info.Name, e.sessionID)
 			} else {
@@ -121,6 +131,8 @@ func (e *consulElection) refresh() {
 		if err != nil {
 			// log
 			e.log.Warnf("[Elector] refresh session err %v", err.Error())
+		} else {
+			e.renewAt = time.Now()
 		}
 	}
 
@@ -130,9 +142,11 @@ func (e *consulElection) refresh() {
 	for {
 		<-e.refushTicker.C
 
+		e.Lock()
 		if e.locked {
 			refushSession()
 		}
+		e.Unlock()
 	}
 }
 
@@ -147,6 +161,25 @@ func (e *consulElection) Run() {
 	}()
 }
 
+// Health 持有锁的节点在 3 个续租周期内没有续租成功时不再就绪
+func (e *consulElection) Health(ctx context.Context) module.HealthStatus {
+	e.Lock()
+	defer e.Unlock()
+
+	if !e.locked {
+		return module.HealthStatus{Live: true, Ready: true, Message: "slave"}
+	}
+
+	if time.Since(e.renewAt) > e.parm.RefushSessionTick*3 {
+		return module.HealthStatus{
+			Live:    true,
+			Message: fmt.Sprintf("master, session last renewed %v ago", time.Since(e.renewAt).Truncate(time.Millisecond)),
+		}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true, Message: "master"}
+}
+
 // Close 释放锁，删除session
 func (e *consulElection) Close() {
 	e.client.ReleaseLock(e.info.Name, e.sessionID)
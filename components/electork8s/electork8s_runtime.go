@@ -2,6 +2,8 @@ package electork8s
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/pojol/braid-go/components/depends/bk8s"
@@ -14,13 +16,18 @@ type k8selector struct {
 	info   meta.ServiceInfo
 	p      Parm
 	locked bool
+	// 获取锁 or 最后一次续租成功的时间
+	renewAt time.Time
 
 	watchTicker   *time.Ticker
 	refreshTicker *time.Ticker
+	done          chan struct{}
 
 	log *blog.Logger
 	ps  module.IPubsub
 	cli *bk8s.Client
+
+	sync.Mutex
 }
 
 func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, ps module.IPubsub, k8scli *bk8s.Client, opts ...Option) module.IElector {
@@ -44,6 +51,7 @@ func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, ps module.IPubsub,
 		info: info,
 		log:  log,
 		cli:  k8scli,
+		done: make(chan struct{}),
 	}
 
 }
@@ -71,6 +79,9 @@ func (e *k8selector) watch() {
 			}
 		}()
 
+		e.Lock()
+		defer e.Unlock()
+
 		if !e.locked {
 			tag, err := e.cli.GetLeases(context.TODO(), e.p.Namespace, e.p.Name)
 			if err != nil {
@@ -79,6 +90,7 @@ func (e *k8selector) watch() {
 
 			if tag == e.info.ID {
 				e.locked = true
+				e.renewAt = time.Now()
 				e.ps.GetTopic(meta.TopicElectionChangeState).Pub(context.TODO(),
 					meta.EncodeStateChangeMsg(meta.EMaster, e.info.ID))
 				e.log.Infof("[braid.elector] acquire lock service %s", e.p.Name)
@@ -92,9 +104,15 @@ func (e *k8selector) watch() {
 	// time.Millisecond * 2000
 	e.watchTicker = time.NewTicker(e.p.WatchTick)
 
+	defer e.watchTicker.Stop()
+
 	for {
-		<-e.watchTicker.C
-		watchLock()
+		select {
+		case <-e.watchTicker.C:
+			watchLock()
+		case <-e.done:
+			return
+		}
 	}
 }
 
@@ -111,17 +129,26 @@ func (e *k8selector) refresh() {
 		if err != nil {
 			// log
 			e.log.Warnf("[braid.elector] refresh session err %v", err.Error())
+		} else {
+			e.renewAt = time.Now()
 		}
 	}
 
 	// time.Millisecond * 1000 * 5
 	e.refreshTicker = time.NewTicker(e.p.RefreshTick)
 
-	for {
-		<-e.refreshTicker.C
+	defer e.refreshTicker.Stop()
 
-		if e.locked {
-			refushSession()
+	for {
+		select {
+		case <-e.refreshTicker.C:
+			e.Lock()
+			if e.locked {
+				refushSession()
+			}
+			e.Unlock()
+		case <-e.done:
+			return
 		}
 	}
 }
@@ -136,9 +163,41 @@ func (e *k8selector) Run() {
 	}()
 }
 
+// Health 持有锁的节点在 3 个续租周期内没有续租成功时不再就绪
+func (e *k8selector) Health(ctx context.Context) module.HealthStatus {
+	e.Lock()
+	defer e.Unlock()
+
+	if !e.locked {
+		return module.HealthStatus{Live: true, Ready: true, Message: "slave"}
+	}
+
+	if time.Since(e.renewAt) > e.p.RefreshTick*3 {
+		return module.HealthStatus{
+			Live:    true,
+			Message: fmt.Sprintf("master, lease last renewed %v ago", time.Since(e.renewAt).Truncate(time.Millisecond)),
+		}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true, Message: "master"}
+}
+
+// Close 停止选举，只有持有锁的节点才会删除租约（释放锁让其他节点接管
 func (e *k8selector) Close() {
+	close(e.done)
+
+	e.Lock()
+	defer e.Unlock()
+
+	if !e.locked {
+		return
+	}
+
 	err := e.cli.RmvLeases(context.TODO(), e.p.Namespace, e.p.Name)
 	if err != nil {
 		e.log.Warnf("[braid.elector] remove leases err %s", err.Error())
+	} else {
+		e.locked = false
+		e.log.Infof("[braid.elector] release lock service %s", e.p.Name)
 	}
 }
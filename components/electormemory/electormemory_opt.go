@@ -0,0 +1,29 @@
+package electormemory
+
+import (
+	"time"
+)
+
+// Parm 选举器配置项
+type Parm struct {
+	WatchTick time.Duration
+
+	// 共享的选举锁，为空时每个实例单独创建
+	Leases *Leases
+}
+
+// Option memory elector config wrapper
+type Option func(*Parm)
+
+func WithWatchTick(t time.Duration) Option {
+	return func(c *Parm) {
+		c.WatchTick = t
+	}
+}
+
+// WithLeases 设置共享的选举锁（NewLeases or GlobalLeases
+func WithLeases(l *Leases) Option {
+	return func(c *Parm) {
+		c.Leases = l
+	}
+}
@@ -0,0 +1,140 @@
+// 实现文件 基于进程内存实现的选举，同名服务中第一个获取到锁的节点成为主节点
+package electormemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// Leases 选举锁，使用同一个 Leases 的同名服务之间进行选举
+type Leases struct {
+	sync.Mutex
+
+	// service name : holder id
+	holder map[string]string
+}
+
+// NewLeases 创建一组选举锁
+func NewLeases() *Leases {
+	return &Leases{
+		holder: make(map[string]string),
+	}
+}
+
+var globalLeases = NewLeases()
+
+// GlobalLeases 进程内全局共享的选举锁（需要通过 WithLeases 显式使用
+func GlobalLeases() *Leases {
+	return globalLeases
+}
+
+func (l *Leases) acquire(name, id string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if holder, ok := l.holder[name]; ok && holder != id {
+		return false
+	}
+
+	l.holder[name] = id
+	return true
+}
+
+func (l *Leases) release(name, id string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.holder[name] == id {
+		delete(l.holder, name)
+	}
+}
+
+type memoryElector struct {
+	info   meta.ServiceInfo
+	p      Parm
+	locked bool
+
+	watchTicker *time.Ticker
+	done        chan struct{}
+
+	log *blog.Logger
+	ps  module.IPubsub
+	l   *Leases
+}
+
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, ps module.IPubsub, opts ...Option) module.IElector {
+
+	p := Parm{
+		WatchTick: time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if p.Leases == nil {
+		p.Leases = NewLeases()
+	}
+
+	return &memoryElector{
+		info: info,
+		p:    p,
+		log:  log,
+		ps:   ps,
+		l:    p.Leases,
+		done: make(chan struct{}),
+	}
+}
+
+func (e *memoryElector) Init() error {
+	return nil
+}
+
+func (e *memoryElector) watch() {
+	watchLock := func() {
+		if e.locked {
+			return
+		}
+
+		if e.l.acquire(e.info.Name, e.info.ID) {
+			e.locked = true
+			e.ps.GetTopic(meta.TopicElectionChangeState).Pub(context.TODO(),
+				meta.EncodeStateChangeMsg(meta.EMaster, e.info.ID))
+			e.log.Infof("[braid.elector] acquire lock service %s", e.info.Name)
+		} else {
+			e.ps.GetTopic(meta.TopicElectionChangeState).Pub(context.TODO(),
+				meta.EncodeStateChangeMsg(meta.ESlave, e.info.ID))
+		}
+	}
+
+	e.watchTicker = time.NewTicker(e.p.WatchTick)
+	defer e.watchTicker.Stop()
+
+	watchLock()
+
+	for {
+		select {
+		case <-e.watchTicker.C:
+			watchLock()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func (e *memoryElector) Run() {
+	go func() {
+		e.watch()
+	}()
+}
+
+// Close 释放锁
+func (e *memoryElector) Close() {
+	close(e.done)
+	e.l.release(e.info.Name, e.info.ID)
+}
@@ -0,0 +1,56 @@
+package electormemory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElection(t *testing.T) {
+
+	log := blog.BuildWithOption()
+	ps := pubsubmemory.BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, log)
+
+	var mu sync.Mutex
+	state := make(map[string]int32)
+
+	channel, _ := ps.GetTopic(meta.TopicElectionChangeState).Sub(context.TODO(), "test-elector")
+	defer channel.Close()
+	channel.Arrived(func(msg *meta.Message) error {
+		smsg := meta.DecodeStateChangeMsg(msg)
+		mu.Lock()
+		state[smsg.ID] = smsg.State
+		mu.Unlock()
+		return nil
+	})
+
+	l := NewLeases()
+	e1 := BuildWithOption(meta.ServiceInfo{ID: "e1", Name: "mem-elector"}, log, ps, WithWatchTick(time.Millisecond*10), WithLeases(l))
+	e2 := BuildWithOption(meta.ServiceInfo{ID: "e2", Name: "mem-elector"}, log, ps, WithWatchTick(time.Millisecond*10), WithLeases(l))
+
+	e1.Init()
+	e1.Run()
+	time.Sleep(time.Millisecond * 50)
+	e2.Init()
+	e2.Run()
+	defer e2.Close()
+
+	time.Sleep(time.Millisecond * 50)
+	mu.Lock()
+	assert.Equal(t, state["e1"], meta.EMaster)
+	assert.Equal(t, state["e2"], meta.ESlave)
+	mu.Unlock()
+
+	// 主节点退出后，从节点接管
+	e1.Close()
+	time.Sleep(time.Millisecond * 50)
+	mu.Lock()
+	assert.Equal(t, state["e2"], meta.EMaster)
+	mu.Unlock()
+}
@@ -0,0 +1,231 @@
+// 实现文件 基于 echo 实现的 http/json 网关，将 POST /{service}/{method} 的请求转换为 rpc 调用
+package gatewayecho
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/labstack/echo/v4"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/depends/btracer"
+	"github.com/pojol/braid-go/components/rpcgrpc/grpcclient"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorBody 调用失败时返回的 json
+type ErrorBody struct {
+	// grpc 错误码
+	Code int `json:"code"`
+
+	Message string `json:"message"`
+}
+
+// httpStatus grpc 错误码对应的 http 状态码
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HTTPStatus grpc 错误码对应的 http 状态码
+func HTTPStatus(code codes.Code) int {
+	if s, ok := httpStatus[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Gateway http/json 网关
+type Gateway struct {
+	info   meta.ServiceInfo
+	parm   Parm
+	log    *blog.Logger
+	client module.IClient
+
+	routes map[string]Route
+
+	e *echo.Echo
+}
+
+// BuildWithOption 构建 http 网关，通过 client 将请求转发到目标服务
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, client module.IClient, opts ...Option) *Gateway {
+
+	p := DefaultGatewayParm
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if client == nil {
+		panic("gateway echo depends rpc client")
+	}
+
+	g := &Gateway{
+		info:   info,
+		parm:   p,
+		log:    log,
+		client: client,
+		routes: make(map[string]Route),
+		e:      echo.New(),
+	}
+
+	for _, route := range p.Routes {
+		g.routes[route.Service+"/"+route.Method] = route
+	}
+
+	g.e.HideBanner = true
+	g.e.HidePort = true
+	g.e.POST("/:service/:method", g.handle, g.trace)
+
+	return g
+}
+
+// trace 使用 btracer.EchoSpan 追踪请求，span 会保存在请求的 context 中
+func (g *Gateway) trace(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if g.parm.Tracer == nil {
+			return next(c)
+		}
+
+		span, err := g.parm.Tracer.GetSpan(btracer.EchoSpan)
+		if err != nil {
+			return next(c)
+		}
+
+		span.Begin(c)
+		defer span.End(c)
+
+		return next(c)
+	}
+}
+
+func newMessage(msg proto.Message) proto.Message {
+	return reflect.New(reflect.TypeOf(msg).Elem()).Interface().(proto.Message)
+}
+
+func (g *Gateway) fail(c echo.Context, code codes.Code, msg string) error {
+	return c.JSON(HTTPStatus(code), ErrorBody{Code: int(code), Message: msg})
+}
+
+func (g *Gateway) handle(c echo.Context) error {
+
+	route, ok := g.routes[c.Param("service")+"/"+c.Param("method")]
+	if !ok {
+		return g.fail(c, codes.NotFound, fmt.Sprintf("route %s not found", c.Request().URL.Path))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, g.parm.BodyLimit+1))
+	if err != nil {
+		return g.fail(c, codes.InvalidArgument, err.Error())
+	}
+	if int64(len(body)) > g.parm.BodyLimit {
+		return c.JSON(http.StatusRequestEntityTooLarge, ErrorBody{
+			Code:    int(codes.InvalidArgument),
+			Message: "request body too large",
+		})
+	}
+
+	req := newMessage(route.Req)
+	if len(bytes.TrimSpace(body)) != 0 {
+		err = jsonpb.Unmarshal(bytes.NewReader(body), req)
+		if err != nil {
+			return g.fail(c, codes.InvalidArgument, err.Error())
+		}
+	}
+
+	ctx := c.Request().Context()
+	if g.parm.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.parm.Timeout)
+		defer cancel()
+	}
+
+	token := c.Request().Header.Get(g.parm.TokenHeader)
+
+	res := newMessage(route.Res)
+	err = g.client.Invoke(ctx, route.Service, route.FullMethod, token, req, res, g.parm.CallOpts...)
+	if err != nil {
+		g.log.Warnf("[braid.gateway] invoke err %s, service = %s, methon = %s, token = %s", err.Error(), route.Service, route.FullMethod, token)
+
+		st := status.New(grpcclient.Code(err), err.Error())
+		var se interface {
+			GRPCStatus() *status.Status
+		}
+		if errors.As(err, &se) {
+			st = se.GRPCStatus()
+		}
+
+		return g.fail(c, st.Code(), st.Message())
+	}
+
+	var buf bytes.Buffer
+	err = (&jsonpb.Marshaler{OrigName: true, EmitDefaults: true}).Marshal(&buf, res)
+	if err != nil {
+		return g.fail(c, codes.Internal, err.Error())
+	}
+
+	return c.JSONBlob(http.StatusOK, buf.Bytes())
+}
+
+// ServeHTTP 可以将网关挂载到其他的 http 服务中
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.e.ServeHTTP(w, r)
+}
+
+func (g *Gateway) Init() error {
+
+	l, err := net.Listen("tcp", g.parm.Listen)
+	if err != nil {
+		return fmt.Errorf("%v [braid.gateway] listen %v err %w", g.info.Name, g.parm.Listen, err)
+	}
+
+	g.e.Listener = l
+	return nil
+}
+
+func (g *Gateway) Run() {
+	go func() {
+		err := g.e.Start(g.parm.Listen)
+		if err != nil && err != http.ErrServerClosed {
+			g.log.Errf("[braid.gateway] serving err %s", err.Error())
+		}
+	}()
+}
+
+func (g *Gateway) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := g.e.Shutdown(ctx); err != nil {
+		g.log.Warnf("[braid.gateway] shutdown err %s", err.Error())
+	}
+}
+
+func (g *Gateway) Name() string {
+	return "GatewayEcho"
+}
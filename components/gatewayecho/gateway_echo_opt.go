@@ -0,0 +1,114 @@
+package gatewayecho
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pojol/braid-go/components/depends/btracer"
+)
+
+// Route 路由 POST /{Service}/{Method} 对应的 rpc 方法和消息类型
+type Route struct {
+	// 目标服务名称
+	Service string
+	// 路由中的方法名
+	Method string
+
+	// grpc 方法全名（如 /proto.listen/routing
+	FullMethod string
+
+	// 请求和返回的消息类型（每次请求会创建新的实例
+	Req proto.Message
+	Res proto.Message
+}
+
+// Parm 网关配置项
+type Parm struct {
+	// 侦听地址
+	Listen string
+
+	// 从 http 头中获取 token 的字段名
+	TokenHeader string
+
+	// 转发调用的超时时间，为 0 时只受请求的 context 约束
+	Timeout time.Duration
+
+	// 请求体的最大字节数
+	BodyLimit int64
+
+	Routes []Route
+
+	// 分布式追踪（使用 btracer.EchoSpan 创建 span，需要在 btracer 中注册 btracer.CreateEchoTraceSpan
+	Tracer btracer.ITracer
+
+	// 转发调用时附加的调用选项（grpcclient.CallOption, grpc.CallOption
+	CallOpts []interface{}
+}
+
+var (
+	DefaultGatewayParm = Parm{
+		Listen:      ":8080",
+		TokenHeader: "X-Braid-Token",
+		BodyLimit:   4 * 1024 * 1024,
+	}
+)
+
+// Option config wraps
+type Option func(*Parm)
+
+// WithListen 网关的侦听地址
+func WithListen(address string) Option {
+	return func(c *Parm) {
+		c.Listen = address
+	}
+}
+
+// WithTokenHeader 从 http 头中获取 token 的字段名（默认 X-Braid-Token
+func WithTokenHeader(header string) Option {
+	return func(c *Parm) {
+		c.TokenHeader = header
+	}
+}
+
+// WithTimeout 转发调用的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Parm) {
+		c.Timeout = timeout
+	}
+}
+
+// WithBodyLimit 请求体的最大字节数，超出时返回 413
+func WithBodyLimit(limit int64) Option {
+	return func(c *Parm) {
+		c.BodyLimit = limit
+	}
+}
+
+// WithRoute 注册路由 POST /{service}/{method}，json 请求体按照 req 的类型转换为 protobuf 后调用 fullMethod
+//
+//	gatewayecho.WithRoute("login", "guest", "/proto.login/guest", &proto.GuestReq{}, &proto.GuestRes{})
+func WithRoute(service, method, fullMethod string, req, res proto.Message) Option {
+	return func(c *Parm) {
+		c.Routes = append(c.Routes, Route{
+			Service:    service,
+			Method:     method,
+			FullMethod: fullMethod,
+			Req:        req,
+			Res:        res,
+		})
+	}
+}
+
+// WithTracer 分布式追踪，转发调用的 context 中携带请求的 span（grpcclient 需要添加 btracer.ClientInterceptor
+func WithTracer(tracer btracer.ITracer) Option {
+	return func(c *Parm) {
+		c.Tracer = tracer
+	}
+}
+
+// WithCallOptions 转发调用时附加的调用选项（如 grpcclient.WithCallStrategy
+func WithCallOptions(opts ...interface{}) Option {
+	return func(c *Parm) {
+		c.CallOpts = append(c.CallOpts, opts...)
+	}
+}
@@ -0,0 +1,126 @@
+package gatewayecho
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/depends/btracer"
+	"github.com/pojol/braid-go/components/rpcgrpc/grpcclient"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type spanKey struct{}
+
+type mockClient struct {
+	module.IClient
+
+	traced bool
+}
+
+func (mc *mockClient) Invoke(ctx context.Context, target, methon, token string, args, reply interface{}, opts ...interface{}) error {
+	mc.traced = ctx.Value(spanKey{}) != nil
+
+	req := args.(*proto.RouteReq)
+	if req.Nod == "denied" {
+		return &grpcclient.RemoteError{Target: target, Methon: methon, Err: status.Error(codes.PermissionDenied, "denied")}
+	} else if req.Nod == "none" {
+		return &grpcclient.CallError{Err: grpcclient.ErrCantFindNode, Target: target}
+	}
+
+	reply.(*proto.RouteRes).ResBody = []byte(target + methon + token + string(req.ReqBody))
+	return nil
+}
+
+func (mc *mockClient) NewStream(ctx context.Context, target, methon, token string, desc *grpc.StreamDesc, opts ...interface{}) (grpc.ClientStream, error) {
+	return nil, nil
+}
+
+type mockSpan struct {
+	begin, end int
+}
+
+func (ms *mockSpan) Begin(ctx interface{}) {
+	ms.begin++
+	c := ctx.(echo.Context)
+	c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), spanKey{}, ms)))
+}
+func (ms *mockSpan) SetTag(key string, val interface{}) {}
+func (ms *mockSpan) GetID() string                      { return "" }
+func (ms *mockSpan) End(ctx interface{})                { ms.end++ }
+
+type mockTracer struct {
+	span *mockSpan
+}
+
+func (mt *mockTracer) GetSpan(strategy string) (btracer.ISpan, error) {
+	if strategy != btracer.EchoSpan {
+		return nil, btracer.ErrFactoryNotExist
+	}
+	return mt.span, nil
+}
+func (mt *mockTracer) GetTracing() interface{} { return nil }
+
+func TestGateway(t *testing.T) {
+
+	client := &mockClient{}
+	tracer := &mockTracer{span: &mockSpan{}}
+
+	g := BuildWithOption(meta.ServiceInfo{Name: "gateway"}, blog.BuildWithDefaultOption(), client,
+		WithRoute("base", "routing", "/proto.listen/routing", &proto.RouteReq{}, &proto.RouteRes{}),
+		WithTracer(tracer),
+		WithBodyLimit(64),
+	)
+
+	post := func(path, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-Braid-Token", "usertoken")
+
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+
+		res := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return rec.Code, res
+	}
+
+	// reqBody 为 bytes 类型，json 中使用 base64 编码
+	code, res := post("/base/routing", `{"nod":"base","reqBody":"aGVsbG8="}`)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, res["resBody"], "YmFzZS9wcm90by5saXN0ZW4vcm91dGluZ3VzZXJ0b2tlbmhlbGxv") // base/proto.listen/routingusertokenhello
+	assert.True(t, client.traced)
+	assert.Equal(t, tracer.span.begin, 1)
+	assert.Equal(t, tracer.span.end, 1)
+
+	code, res = post("/base/routing", `{"nod":"denied"}`)
+	assert.Equal(t, code, http.StatusForbidden)
+	assert.Equal(t, res["code"], float64(codes.PermissionDenied))
+	assert.Equal(t, res["message"], "denied")
+
+	code, _ = post("/base/routing", `{"nod":"none"}`)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+
+	code, _ = post("/base/unknown", `{}`)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _ = post("/base/routing", `{"unknown":1}`)
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	code, _ = post("/base/routing", `{"nod":"`+strings.Repeat("a", 64)+`"}`)
+	assert.Equal(t, code, http.StatusRequestEntityTooLarge)
+
+	assert.Equal(t, HTTPStatus(codes.DeadlineExceeded), http.StatusGatewayTimeout)
+	assert.Equal(t, HTTPStatus(codes.Code(100)), http.StatusInternalServerError)
+}
@@ -4,10 +4,13 @@ package balancer
 
 import "github.com/pojol/braid-go/module/meta"
 
+// Filter 节点过滤器，返回 false 的节点不会被选取（如重试时排除已经尝试过的节点
+type Filter func(nod meta.Node) bool
+
 // IPicker 选取器
 type IPicker interface {
-	// Get 从当前的负载均衡算法中，选取一个匹配的节点
-	Get() (nod meta.Node, err error)
+	// Get 从当前的负载均衡算法中，选取一个匹配（通过所有过滤器）的节点
+	Get(filters ...Filter) (nod meta.Node, err error)
 
 	// Add 为当前的服务添加一个新的节点 service gate : [ gate1, gate2 ]
 	Add(meta.Node)
@@ -26,7 +29,20 @@ type IBalancer interface {
 	// Pick 为 target 服务选取一个合适的节点
 	//
 	// strategy 选取所使用的策略，在构建阶段通过 opt 传入
-	Pick(strategy string, target string) (meta.Node, error)
+	//
+	// filters 节点过滤器，没有节点通过过滤器时返回错误
+	Pick(strategy string, target string, filters ...Filter) (meta.Node, error)
+
+	// Start 在向 target 服务的节点发起调用之前调用，返回的函数在调用结束后执行（failed 节点是否故障
+	//
+	// 用于统计节点的延迟和进行中的请求数（StrategyP2C
+	Start(target string, address string) (done func(failed bool))
+
+	// Nodes 获取 target 服务当前所有通过过滤器的节点（用于广播调用
+	Nodes(target string, filters ...Filter) []meta.Node
+
+	// All 获取所有服务当前通过过滤器的节点（用于向后订阅节点事件的回调回放已知的节点
+	All(filters ...Filter) []meta.Node
 
 	Run()
 
@@ -0,0 +1,74 @@
+package balancer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickFilter(t *testing.T) {
+
+	serviceName := "TestPickFilter"
+	info := meta.ServiceInfo{ID: "TestPickFilter", Name: serviceName}
+	log := blog.BuildWithDefaultOption()
+
+	ps := pubsubmemory.BuildWithOption(info, log)
+	bg := BuildWithOption(info, log, ps)
+
+	bg.Init()
+	bg.Run()
+	defer bg.Close()
+
+	for _, id := range []string{"A", "B", "C"} {
+		ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(), meta.EncodeUpdateMsg(
+			meta.TopicDiscoverServiceNodeAdd,
+			meta.Node{ID: id, Address: id, Name: serviceName},
+		))
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	exclude := func(ids ...string) Filter {
+		return func(nod meta.Node) bool {
+			for _, id := range ids {
+				if nod.ID == id {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	for _, strategy := range []string{StrategyRandom, StrategySwrr} {
+		for i := 0; i < 20; i++ {
+			nod, err := bg.Pick(strategy, serviceName, exclude("A", "B"))
+			assert.Nil(t, err)
+			assert.Equal(t, nod.ID, "C")
+
+			nod, err = bg.Pick(strategy, serviceName, exclude("A"))
+			assert.Nil(t, err)
+			assert.NotEqual(t, nod.ID, "A")
+		}
+
+		_, err := bg.Pick(strategy, serviceName, exclude("A", "B", "C"))
+		assert.NotNil(t, err)
+	}
+
+	assert.Equal(t, len(bg.Nodes(serviceName)), 3)
+	assert.Equal(t, len(bg.Nodes(serviceName, exclude("A"))), 2)
+	assert.Equal(t, len(bg.Nodes("unknown")), 0)
+
+	ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(), meta.EncodeUpdateMsg(
+		meta.TopicDiscoverServiceNodeAdd,
+		meta.Node{ID: "D", Address: "D", Name: "other"},
+	))
+	time.Sleep(time.Millisecond * 100)
+
+	assert.Equal(t, len(bg.All()), 4)
+	assert.Equal(t, len(bg.All(exclude("A", "D"))), 2)
+}
@@ -19,18 +19,34 @@ const (
 
 	StrategyRandom = "strategy_random"
 	StrategySwrr   = "strategy_swrr"
+
+	// StrategyP2C 根据调用的延迟和进行中的请求数选取节点（需要通过 Start 反馈调用的结果
+	StrategyP2C = "strategy_p2c"
 )
 
+// accept 节点是否通过所有的过滤器
+func accept(nod meta.Node, filters []Filter) bool {
+	for _, filter := range filters {
+		if filter != nil && !filter(nod) {
+			return false
+		}
+	}
+	return true
+}
+
 type balancerStrategy struct {
-	randomPicker IPicker
+	randomPicker *randomBalancer
 	swrrPicker   IPicker
+	p2cPicker    *p2cBalancer
 }
 
-func (s *balancerStrategy) Get(strategy string) (meta.Node, error) {
+func (s *balancerStrategy) Get(strategy string, filters ...Filter) (meta.Node, error) {
 	if strategy == StrategyRandom {
-		return s.randomPicker.Get()
+		return s.randomPicker.Get(filters...)
 	} else if strategy == StrategySwrr {
-		return s.swrrPicker.Get()
+		return s.swrrPicker.Get(filters...)
+	} else if strategy == StrategyP2C {
+		return s.p2cPicker.Get(filters...)
 	}
 	return meta.Node{}, fmt.Errorf("not picker strategy %v", strategy)
 }
@@ -38,15 +54,19 @@ func (s *balancerStrategy) Get(strategy string) (meta.Node, error) {
 func (s *balancerStrategy) Add(nod meta.Node) {
 	s.randomPicker.Add(nod)
 	s.swrrPicker.Add(nod)
+	s.p2cPicker.Add(nod)
 }
 
 func (s *balancerStrategy) Rmv(nod meta.Node) {
 	s.randomPicker.Rmv(nod)
 	s.swrrPicker.Rmv(nod)
+	s.p2cPicker.Rmv(nod)
 }
 
 func (s *balancerStrategy) Update(nod meta.Node) {
+	s.randomPicker.Update(nod)
 	s.swrrPicker.Update(nod)
+	s.p2cPicker.Update(nod)
 }
 
 type baseBalancerGroup struct {
@@ -97,6 +117,7 @@ func (bbg *baseBalancerGroup) Run() {
 				bbg.picker[dmsg.Nod.Name] = &balancerStrategy{
 					randomPicker: &randomBalancer{},
 					swrrPicker:   &swrrBalancer{},
+					p2cPicker:    &p2cBalancer{},
 				}
 			}
 
@@ -110,7 +131,7 @@ func (bbg *baseBalancerGroup) Run() {
 			}
 
 			bbg.Unlock()
-		} else if dmsg.Event == meta.TopicDiscoverServiceUpdate {
+		} else if dmsg.Event == meta.TopicDiscoverServiceNodeUpdate {
 			bbg.Lock()
 
 			if _, ok := bbg.picker[dmsg.Nod.Name]; ok {
@@ -125,7 +146,7 @@ func (bbg *baseBalancerGroup) Run() {
 
 }
 
-func (bbg *baseBalancerGroup) Pick(strategy string, target string) (meta.Node, error) {
+func (bbg *baseBalancerGroup) Pick(strategy string, target string, filters ...Filter) (meta.Node, error) {
 
 	bbg.RLock()
 	defer bbg.RUnlock()
@@ -135,9 +156,11 @@ func (bbg *baseBalancerGroup) Pick(strategy string, target string) (meta.Node, e
 
 	if _, ok := bbg.picker[target]; ok {
 		if strategy == StrategyRandom {
-			nod, err = bbg.picker[target].randomPicker.Get()
+			nod, err = bbg.picker[target].randomPicker.Get(filters...)
 		} else if strategy == StrategySwrr {
-			nod, err = bbg.picker[target].swrrPicker.Get()
+			nod, err = bbg.picker[target].swrrPicker.Get(filters...)
+		} else if strategy == StrategyP2C {
+			nod, err = bbg.picker[target].p2cPicker.Get(filters...)
 		}
 	}
 
@@ -145,6 +168,47 @@ func (bbg *baseBalancerGroup) Pick(strategy string, target string) (meta.Node, e
 	return nod, err
 }
 
+func (bbg *baseBalancerGroup) Start(target string, address string) func(failed bool) {
+
+	bbg.RLock()
+	defer bbg.RUnlock()
+
+	if _, ok := bbg.picker[target]; ok {
+		if stat := bbg.picker[target].p2cPicker.stat(address); stat != nil {
+			return stat.start()
+		}
+	}
+
+	return func(bool) {}
+}
+
+func (bbg *baseBalancerGroup) Nodes(target string, filters ...Filter) []meta.Node {
+
+	bbg.RLock()
+	defer bbg.RUnlock()
+
+	if _, ok := bbg.picker[target]; !ok {
+		return nil
+	}
+
+	return bbg.picker[target].randomPicker.list(filters...)
+}
+
+func (bbg *baseBalancerGroup) All(filters ...Filter) []meta.Node {
+
+	bbg.RLock()
+	defer bbg.RUnlock()
+
+	var nods []meta.Node
+	for _, s := range bbg.picker {
+		nods = append(nods, s.randomPicker.list(filters...)...)
+	}
+
+	return nods
+}
+
 func (bbg *baseBalancerGroup) Close() {
-	bbg.serviceUpdate.Close()
+	if bbg.serviceUpdate != nil {
+		bbg.serviceUpdate.Close()
+	}
 }
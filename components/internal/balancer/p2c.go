@@ -0,0 +1,189 @@
+// 实现文件 balancerp2c 基于延迟反馈的负载均衡算法实现（peak-EWMA + power of two choices
+package balancer
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pojol/braid-go/module/meta"
+)
+
+const (
+	// 延迟 EWMA 的衰减时间，越小对延迟的变化越敏感
+	p2cDecay = time.Second * 10
+
+	// 还没有延迟样本但有进行中请求的节点，以及调用失败时使用的延迟
+	p2cPenalty = time.Second
+)
+
+// p2cStat 节点的延迟统计
+type p2cStat struct {
+	// 延迟的 EWMA（纳秒，延迟升高时直接取峰值，降低时按照时间衰减
+	ewma  float64
+	stamp time.Time
+
+	// 进行中的请求数
+	inflight int64
+
+	sync.Mutex
+}
+
+// weight 距离上一次样本经过的时间对应的衰减系数，需要在持有锁的情况下调用
+func (s *p2cStat) weight(now time.Time) float64 {
+	elapsed := float64(now.Sub(s.stamp))
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Exp(-elapsed / float64(p2cDecay))
+}
+
+// observe 记录一次调用的延迟，需要在持有锁的情况下调用
+func (s *p2cStat) observe(rtt float64, now time.Time) {
+
+	if rtt > s.ewma {
+		s.ewma = rtt
+	} else {
+		w := s.weight(now)
+		s.ewma = s.ewma*w + rtt*(1-w)
+	}
+
+	s.stamp = now
+}
+
+// cost 节点当前的负载（延迟 * 进行中的请求数
+func (s *p2cStat) cost() float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	// 没有新的样本时，延迟按照时间衰减（只在计算时衰减，不会作为样本写入
+	ewma := s.ewma * s.weight(time.Now())
+
+	if ewma == 0 && s.inflight != 0 {
+		return float64(p2cPenalty) + float64(s.inflight)
+	}
+
+	return ewma * float64(s.inflight+1)
+}
+
+func (s *p2cStat) start() func(failed bool) {
+	s.Lock()
+	s.inflight++
+	s.Unlock()
+
+	begin := time.Now()
+
+	return func(failed bool) {
+		now := time.Now()
+		rtt := float64(now.Sub(begin))
+		if failed && rtt < float64(p2cPenalty) {
+			rtt = float64(p2cPenalty)
+		}
+
+		s.Lock()
+		s.inflight--
+		s.observe(rtt, now)
+		s.Unlock()
+	}
+}
+
+// p2cBalancer 随机选取两个节点，选择其中负载较低的节点
+type p2cBalancer struct {
+	nods  []meta.Node
+	stats map[string]*p2cStat
+}
+
+func (pb *p2cBalancer) exist(id string) (int, bool) {
+	for k, v := range pb.nods {
+		if v.ID == id {
+			return k, true
+		}
+	}
+
+	return -1, false
+}
+
+func (pb *p2cBalancer) stat(address string) *p2cStat {
+	return pb.stats[address]
+}
+
+// cost 节点的负载，没有统计信息的节点视为没有负载
+func (pb *p2cBalancer) cost(address string) float64 {
+	if s := pb.stat(address); s != nil {
+		return s.cost()
+	}
+	return 0
+}
+
+func (pb *p2cBalancer) Add(nod meta.Node) {
+
+	if _, ok := pb.exist(nod.ID); ok {
+		return
+	}
+
+	if pb.stats == nil {
+		pb.stats = make(map[string]*p2cStat)
+	}
+
+	pb.nods = append(pb.nods, nod)
+	pb.stats[nod.Address] = &p2cStat{stamp: time.Now()}
+}
+
+func (pb *p2cBalancer) Rmv(nod meta.Node) {
+
+	idx, ok := pb.exist(nod.ID)
+	if !ok {
+		return
+	}
+
+	delete(pb.stats, pb.nods[idx].Address)
+	pb.nods = append(pb.nods[:idx], pb.nods[idx+1:]...)
+}
+
+func (pb *p2cBalancer) Update(nod meta.Node) {
+
+	idx, ok := pb.exist(nod.ID)
+	if !ok {
+		return
+	}
+
+	// 地址变更后使用新的统计信息（旧地址上进行中的请求仍然记录在旧的统计中
+	if old := pb.nods[idx].Address; old != nod.Address {
+		delete(pb.stats, old)
+		pb.stats[nod.Address] = &p2cStat{stamp: time.Now()}
+	}
+
+	pb.nods[idx] = nod
+}
+
+func (pb *p2cBalancer) Get(filters ...Filter) (meta.Node, error) {
+
+	nods := make([]meta.Node, 0, len(pb.nods))
+	for _, nod := range pb.nods {
+		if accept(nod, filters) {
+			nods = append(nods, nod)
+		}
+	}
+
+	if len(nods) <= 0 {
+		return meta.Node{}, errors.New("empty")
+	}
+
+	if len(nods) == 1 {
+		return nods[0], nil
+	}
+
+	a := rand.Intn(len(nods))
+	b := rand.Intn(len(nods) - 1)
+	if b >= a {
+		b++
+	}
+
+	if pb.cost(nods[b].Address) < pb.cost(nods[a].Address) {
+		a = b
+	}
+
+	return nods[a], nil
+}
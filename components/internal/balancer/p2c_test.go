@@ -0,0 +1,123 @@
+package balancer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestP2CBalancer(t *testing.T) {
+
+	pb := &p2cBalancer{}
+	_, err := pb.Get()
+	assert.NotNil(t, err)
+
+	for _, id := range []string{"A", "B", "C"} {
+		pb.Add(meta.Node{ID: id, Address: id})
+	}
+
+	now := time.Now()
+	pb.stat("A").observe(float64(time.Millisecond), now)
+	pb.stat("B").observe(float64(time.Millisecond*100), now)
+	pb.stat("C").observe(float64(time.Millisecond*100), now)
+
+	// 两两比较时总是选取延迟较低的节点
+	for i := 0; i < 100; i++ {
+		nod, err := pb.Get(func(nod meta.Node) bool { return nod.ID != "C" })
+		assert.Nil(t, err)
+		assert.Equal(t, nod.ID, "A")
+	}
+
+	// 进行中的请求数较多的节点负载更高
+	var dones []func(bool)
+	for i := 0; i < 200; i++ {
+		dones = append(dones, pb.stat("A").start())
+	}
+	for i := 0; i < 100; i++ {
+		nod, _ := pb.Get(func(nod meta.Node) bool { return nod.ID != "C" })
+		assert.Equal(t, nod.ID, "B")
+	}
+	for _, done := range dones {
+		done(false)
+	}
+	assert.Equal(t, pb.stat("A").inflight, int64(0))
+
+	// 延迟升高时直接取峰值
+	pb.stat("A").observe(float64(time.Second), time.Now())
+	assert.Equal(t, pb.stat("A").ewma, float64(time.Second))
+
+	// 失败的调用按照 p2cPenalty 计算延迟
+	done := pb.stat("B").start()
+	done(true)
+	assert.Equal(t, pb.stat("B").ewma, float64(p2cPenalty))
+
+	// 读取负载不会改变延迟的统计
+	ewma := pb.stat("B").ewma
+	pb.stat("B").cost()
+	assert.Equal(t, pb.stat("B").ewma, ewma)
+
+	// 地址变更后在新的地址上重新统计
+	pb.Update(meta.Node{ID: "C", Address: "C2"})
+	assert.Nil(t, pb.stat("C"))
+	assert.NotNil(t, pb.stat("C2"))
+	for i := 0; i < 20; i++ {
+		_, err := pb.Get()
+		assert.Nil(t, err)
+	}
+
+	pb.Rmv(meta.Node{ID: "A"})
+	assert.Nil(t, pb.stat("A"))
+	for i := 0; i < 20; i++ {
+		nod, _ := pb.Get()
+		assert.NotEqual(t, nod.ID, "A")
+	}
+}
+
+func TestP2CStrategy(t *testing.T) {
+
+	serviceName := "TestP2CStrategy"
+	info := meta.ServiceInfo{ID: "TestP2CStrategy", Name: serviceName}
+	log := blog.BuildWithDefaultOption()
+
+	ps := pubsubmemory.BuildWithOption(info, log)
+	bg := BuildWithOption(info, log, ps)
+
+	bg.Init()
+	bg.Run()
+	defer bg.Close()
+
+	for _, id := range []string{"fast", "slow"} {
+		ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(), meta.EncodeUpdateMsg(
+			meta.TopicDiscoverServiceNodeAdd,
+			meta.Node{ID: id, Address: id, Name: serviceName},
+		))
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(bg.Nodes(serviceName)) == 2
+	}, time.Second, time.Millisecond*5)
+
+	// 通过 Start 反馈调用的延迟，slow 节点的调用耗时 20ms
+	calls := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		nod, err := bg.Pick(StrategyP2C, serviceName)
+		assert.Nil(t, err)
+
+		done := bg.Start(serviceName, nod.Address)
+		if nod.ID == "slow" {
+			time.Sleep(time.Millisecond * 20)
+		}
+		done(false)
+
+		calls[nod.ID]++
+	}
+
+	// 延迟较高的节点只会在没有延迟样本时被选取
+	assert.True(t, calls["slow"] <= 2, calls)
+	assert.Equal(t, calls["fast"]+calls["slow"], 40)
+}
@@ -43,15 +43,39 @@ func (rb *randomBalancer) Rmv(nod meta.Node) {
 }
 
 func (rb *randomBalancer) Update(nod meta.Node) {
-	//
+
+	idx, ok := rb.exist(nod.ID)
+	if !ok {
+		return
+	}
+
+	rb.nods[idx] = nod
+}
+
+// list 获取通过过滤器的节点
+func (rb *randomBalancer) list(filters ...Filter) []meta.Node {
+
+	nods := make([]meta.Node, 0, len(rb.nods))
+	for _, nod := range rb.nods {
+		if accept(nod, filters) {
+			nods = append(nods, nod)
+		}
+	}
+
+	return nods
 }
 
-func (rb *randomBalancer) Get() (meta.Node, error) {
+func (rb *randomBalancer) Get(filters ...Filter) (meta.Node, error) {
+
+	nods := rb.nods
+	if len(filters) != 0 {
+		nods = rb.list(filters...)
+	}
 
-	if len(rb.nods) <= 0 {
+	if len(nods) <= 0 {
 		return meta.Node{}, errors.New("empty")
 	}
 
 	rand.Seed(time.Now().UnixNano())
-	return rb.nods[rand.Intn(len(rb.nods))], nil
+	return nods[rand.Intn(len(nods))], nil
 }
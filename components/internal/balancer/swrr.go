@@ -38,10 +38,10 @@ func (wr *swrrBalancer) isExist(id string) (int, bool) {
 	return -1, false
 }
 
-// Pick 执行算法，选取节点
-func (wr *swrrBalancer) Get() (meta.Node, error) {
+// Pick 执行算法，选取节点（只在通过过滤器的节点中选取
+func (wr *swrrBalancer) Get(filters ...Filter) (meta.Node, error) {
 	var tmpWeight int
-	var idx int
+	var idx = -1
 	wr.Lock()
 	defer wr.Unlock()
 
@@ -49,17 +49,31 @@ func (wr *swrrBalancer) Get() (meta.Node, error) {
 		return meta.Node{}, errors.New("empty")
 	}
 
+	candidates := make([]bool, len(wr.nods))
 	for k, v := range wr.nods {
+		candidates[k] = accept(v.orgNod, filters)
+		if !candidates[k] {
+			continue
+		}
+
+		if idx == -1 {
+			idx = k
+		}
+
 		if tmpWeight < v.curWeight+wr.totalWeight {
 			tmpWeight = v.curWeight + wr.totalWeight
 			idx = k
 		}
 	}
 
+	if idx == -1 {
+		return meta.Node{}, errors.New("empty")
+	}
+
 	for k := range wr.nods {
 		if k == idx {
 			wr.nods[idx].curWeight = wr.nods[idx].curWeight - wr.totalWeight + wr.nods[idx].orgNod.GetWidget()
-		} else {
+		} else if candidates[k] {
 			wr.nods[k].curWeight += wr.nods[k].orgNod.GetWidget()
 		}
 	}
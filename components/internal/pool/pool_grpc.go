@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -23,6 +24,8 @@ var (
 	ErrPoolClosed = errors.New("grpc pool is closed")
 	// ErrPoolCapacity 错误的容量设置
 	ErrPoolCapacity = errors.New("grpc pool wrong capacity")
+	// ErrPoolEmpty 池中没有可以共享的连接（连接都被 Get 取出
+	ErrPoolEmpty = errors.New("grpc pool has no shareable client")
 )
 
 // GRPCPool grpc client pool
@@ -54,6 +57,16 @@ type ClientConn struct {
 		通过unhealthy标记，pool会在使用这个连接的时候将其回收，并重新获取一个新的连接。
 	*/
 	unhealthy bool
+
+	// shared 通过 Shared 获取的连接的共享状态，连接的各个副本共用
+	shared *sharedState
+}
+
+// sharedState 共享连接的状态
+type sharedState struct {
+	refs      int32 // 正在使用这个连接的调用数量
+	used      int64 // 最后一次使用结束的时间（UnixNano
+	unhealthy int32
 }
 
 // NewGRPCPool 新建 grpc 连接池
@@ -145,8 +158,79 @@ func (p *GRPCPool) Get(ctx context.Context) (*ClientConn, error) {
 	return &wrapper, err
 }
 
+// Shared 按照轮询获取池中的一个连接，连接不会被独占（grpc 在同一个连接上复用多个调用
+//
+//	获取时不会阻塞等待，连接在第一次被获取时才会创建；使用结束后需要调用 Put
+//	闲置超过 idleTimeout 或者被标记为 unhealthy 的连接会被关闭并重新创建
+//	同一个池只使用 Shared 或者只使用 Get，不要混用
+func (p *GRPCPool) Shared() (*ClientConn, error) {
+	var err error
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.clients == nil {
+		return nil, ErrPoolClosed
+	}
+
+	var wrapper ClientConn
+	select {
+	case wrapper = <-p.clients:
+	default:
+		return nil, ErrPoolEmpty
+	}
+
+	if wrapper.ClientConn != nil && wrapper.shared == nil {
+		// NewGRPCPool 中预先创建的连接
+		wrapper.shared = &sharedState{used: wrapper.timeUsed.UnixNano()}
+	}
+
+	if wrapper.ClientConn != nil {
+		state := wrapper.shared
+		idle := p.idleTimeout > 0 && atomic.LoadInt32(&state.refs) == 0 &&
+			time.Unix(0, atomic.LoadInt64(&state.used)).Add(p.idleTimeout).Before(time.Now())
+
+		if idle || atomic.LoadInt32(&state.unhealthy) == 1 {
+			wrapper.ClientConn.Close()
+			wrapper.ClientConn = nil
+		}
+	}
+
+	if wrapper.ClientConn == nil {
+		wrapper.ClientConn, err = p.factory()
+		if err != nil {
+			p.clients <- ClientConn{
+				pool: p,
+			}
+			return nil, err
+		}
+
+		wrapper.timeInitiated = time.Now()
+		wrapper.shared = &sharedState{}
+	}
+
+	atomic.AddInt32(&wrapper.shared.refs, 1)
+	atomic.StoreInt64(&wrapper.shared.used, time.Now().UnixNano())
+	wrapper.timeUsed = time.Now()
+
+	// 放回队尾，下一次获取时返回下一个连接
+	p.clients <- wrapper
+
+	return &wrapper, nil
+}
+
 // Put 放回池中
 func (c *ClientConn) Put() error {
+	if c.shared != nil {
+		// 共享的连接仍然在池中，只需要结束引用
+		if c.ClientConn != nil {
+			atomic.AddInt32(&c.shared.refs, -1)
+			atomic.StoreInt64(&c.shared.used, time.Now().UnixNano())
+			c.ClientConn = nil
+		}
+		return nil
+	}
+
 	wrapper := ClientConn{
 		pool:       c.pool,
 		ClientConn: c.ClientConn,
@@ -194,8 +278,13 @@ func (p *GRPCPool) Close() {
 }
 
 // Unhealthy 将连接设置为不健康状态
+//
+//	共享的连接在下一次被 Shared 获取时重新创建
 func (c *ClientConn) Unhealthy() {
 	c.unhealthy = true
+	if c.shared != nil {
+		atomic.StoreInt32(&c.shared.unhealthy, 1)
+	}
 }
 
 // Capacity 返回池的总容量
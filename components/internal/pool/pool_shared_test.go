@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestShared(t *testing.T) {
+
+	var dials int
+	p, err := NewGRPCPool(func() (*grpc.ClientConn, error) {
+		dials++
+		return grpc.Dial("localhost:1205", grpc.WithInsecure())
+	}, 0, 2, time.Second*120)
+	assert.Nil(t, err)
+
+	// 第一次获取时才会创建连接
+	assert.Equal(t, dials, 0)
+
+	a, err := p.Shared()
+	assert.Nil(t, err)
+	b, err := p.Shared()
+	assert.Nil(t, err)
+	assert.NotSame(t, a.ClientConn, b.ClientConn)
+	assert.Equal(t, dials, 2)
+
+	// 轮询复用已经创建的连接，获取的连接不需要先放回
+	for i := 0; i < 10; i++ {
+		conn, err := p.Shared()
+		assert.Nil(t, err)
+		if i%2 == 0 {
+			assert.Same(t, conn.ClientConn, a.ClientConn)
+		} else {
+			assert.Same(t, conn.ClientConn, b.ClientConn)
+		}
+		conn.Put()
+	}
+	assert.Equal(t, dials, 2)
+	assert.Equal(t, p.Available(), 2)
+
+	// 标记为 unhealthy 的连接在下一次获取时重新创建
+	ac := a.ClientConn
+	a.Unhealthy()
+	a.Put()
+	b.Put()
+
+	conn, err := p.Shared()
+	assert.Nil(t, err)
+	assert.NotSame(t, conn.ClientConn, ac)
+	assert.Equal(t, dials, 3)
+	conn.Put()
+
+	p.Close()
+	p.Close()
+
+	_, err = p.Shared()
+	assert.Equal(t, err, ErrPoolClosed)
+}
+
+func TestSharedIdle(t *testing.T) {
+
+	var dials int
+	p, err := NewGRPCPool(func() (*grpc.ClientConn, error) {
+		dials++
+		return grpc.Dial("localhost:1205", grpc.WithInsecure())
+	}, 1, 1, time.Millisecond*20)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	// 预先创建的连接
+	a, err := p.Shared()
+	assert.Nil(t, err)
+	assert.Equal(t, dials, 1)
+
+	// 仍然在使用中的连接不会因为闲置被关闭
+	time.Sleep(time.Millisecond * 40)
+	b, err := p.Shared()
+	assert.Nil(t, err)
+	assert.Same(t, a.ClientConn, b.ClientConn)
+	ac := a.ClientConn
+	a.Put()
+	b.Put()
+
+	// 闲置超时的连接会被关闭并重新创建
+	time.Sleep(time.Millisecond * 40)
+	c, err := p.Shared()
+	assert.Nil(t, err)
+	assert.NotSame(t, ac, c.ClientConn)
+	assert.Equal(t, dials, 2)
+	c.Put()
+}
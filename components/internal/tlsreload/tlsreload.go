@@ -0,0 +1,263 @@
+// 证书的热加载，证书文件变更后新的连接会使用新的证书（已经建立的连接不受影响
+package tlsreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoCertificate 没有配置证书
+	ErrNoCertificate = errors.New("tls certificate not set")
+)
+
+// Reloader 从文件加载证书和 CA，并定时检查文件的变更
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	cert *tls.Certificate
+	pool *x509.CertPool
+
+	modAt map[string]time.Time
+
+	done chan struct{}
+	once sync.Once
+
+	sync.RWMutex
+}
+
+// New 创建一个 Reloader，文件为空时不加载（caFile 为空时使用系统的根证书
+func New(certFile, keyFile, caFile string) *Reloader {
+	return &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		modAt:    make(map[string]time.Time),
+		done:     make(chan struct{}),
+	}
+}
+
+func (r *Reloader) files() []string {
+	lst := []string{}
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file != "" {
+			lst = append(lst, file)
+		}
+	}
+	return lst
+}
+
+// changed 文件是否有变更（第一次调用时总是返回 true
+func (r *Reloader) changed() (bool, error) {
+	changed := false
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+
+		if !info.ModTime().Equal(r.modAt[file]) {
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+// Load 加载证书文件，加载失败时保留之前的证书
+func (r *Reloader) Load() error {
+
+	var cert *tls.Certificate
+	var pool *x509.CertPool
+
+	modAt := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modAt[file] = info.ModTime()
+	}
+
+	if r.certFile != "" || r.keyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("load key pair %v %v err : %w", r.certFile, r.keyFile, err)
+		}
+		cert = &c
+	}
+
+	if r.caFile != "" {
+		byt, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(byt) {
+			return fmt.Errorf("load ca %v err : no certificate found", r.caFile)
+		}
+	}
+
+	r.Lock()
+	r.cert = cert
+	r.pool = pool
+	r.modAt = modAt
+	r.Unlock()
+
+	return nil
+}
+
+// Watch 每隔 interval 检查一次文件的变更，变更后重新加载
+//
+//	onReload 每次重新加载后的回调（err 为空时表示加载成功
+func (r *Reloader) Watch(interval time.Duration, onReload func(err error)) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.RLock()
+				changed, err := r.changed()
+				r.RUnlock()
+
+				if err == nil && !changed {
+					continue
+				}
+
+				if err == nil {
+					err = r.Load()
+				}
+
+				if onReload != nil {
+					onReload(err)
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止检查文件的变更
+func (r *Reloader) Close() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
+
+// Certificate 当前的证书
+func (r *Reloader) Certificate() (*tls.Certificate, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return r.cert, nil
+}
+
+// Pool 当前的 CA，没有设置 CA 文件时返回 nil
+func (r *Reloader) Pool() *x509.CertPool {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.pool
+}
+
+// ServerConfig 服务端的 tls 配置，设置了 CA 文件时要求并验证客户端的证书（mTLS
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, err := r.Certificate()
+			if err != nil {
+				return nil, err
+			}
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+			}
+
+			if pool := r.Pool(); pool != nil {
+				cfg.ClientCAs = pool
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig 客户端的 tls 配置，使用当前的 CA 验证服务端的证书，设置了证书文件时向服务端提供客户端证书（mTLS
+//
+//	serverName 服务端证书中需要包含的名称（域名 or ip
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := r.Certificate()
+			if err != nil {
+				// 没有客户端证书时发送空的证书，由服务端决定是否拒绝
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+		// 证书由 VerifyConnection 使用热加载的 CA 进行验证
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return r.verify(cs, serverName)
+		},
+	}
+}
+
+func (r *Reloader) verify(cs tls.ConnectionState, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls peer certificate not found")
+	}
+
+	if serverName == "" {
+		serverName = cs.ServerName
+	}
+	if serverName == "" {
+		return errors.New("tls server name not set")
+	}
+
+	pool := r.Pool()
+	if pool == nil {
+		var err error
+		pool, err = x509.SystemCertPool()
+		if err != nil {
+			return err
+		}
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,126 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writePem(t *testing.T, file string, typ string, byt []byte) {
+	f, err := os.Create(file)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	assert.Nil(t, pem.Encode(f, &pem.Block{Type: typ, Bytes: byt}))
+}
+
+// newCert 生成证书，ca 为空时生成自签名的 CA
+func newCert(t *testing.T, dir, name string, serial int64, ca *x509.Certificate, caKey *ecdsa.PrivateKey, hosts ...string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		ca, caKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	keyder, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	writePem(t, filepath.Join(dir, name+".crt"), "CERTIFICATE", der)
+	writePem(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyder)
+
+	return cert, key
+}
+
+func TestReload(t *testing.T) {
+
+	dir := t.TempDir()
+	ca, caKey := newCert(t, dir, "ca", 1, nil, nil)
+	newCert(t, dir, "server", 2, ca, caKey, "base", "127.0.0.1")
+
+	r := New(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt"))
+	_, err := r.Certificate()
+	assert.Equal(t, err, ErrNoCertificate)
+
+	assert.Nil(t, r.Load())
+	defer r.Close()
+
+	cert, err := r.Certificate()
+	assert.Nil(t, err)
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	assert.Equal(t, leaf.SerialNumber.Int64(), int64(2))
+
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+	assert.Nil(t, r.verify(cs, "base"))
+	assert.Nil(t, r.verify(cs, "127.0.0.1"))
+	assert.NotNil(t, r.verify(cs, "other"))
+	assert.NotNil(t, r.verify(cs, ""))
+
+	newCert(t, dir, "server", 3, ca, caKey, "base")
+	future := time.Now().Add(time.Second)
+	for _, file := range []string{"server.crt", "server.key"} {
+		assert.Nil(t, os.Chtimes(filepath.Join(dir, file), future, future))
+	}
+
+	reloaded := make(chan error, 1)
+	r.Watch(time.Millisecond*10, func(err error) {
+		select {
+		case reloaded <- err:
+		default:
+		}
+	})
+
+	select {
+	case err = <-reloaded:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("reload timeout")
+	}
+
+	cert, _ = r.Certificate()
+	leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+	assert.Equal(t, leaf.SerialNumber.Int64(), int64(3))
+
+	// 加载失败时保留之前的证书
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "server.key"), []byte("bad"), 0644))
+	assert.NotNil(t, r.Load())
+	cert, err = r.Certificate()
+	assert.Nil(t, err)
+	assert.NotNil(t, cert)
+}
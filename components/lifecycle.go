@@ -0,0 +1,481 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pojol/braid-go/module"
+)
+
+// 生命周期的阶段名称
+const (
+	PhaseInit  = "init"
+	PhaseRun   = "run"
+	PhaseClose = "close"
+)
+
+// LifecycleComponent 被生命周期管理的组件
+type LifecycleComponent struct {
+	Name string
+
+	// 依赖的组件名称，被依赖的组件会先于自身 Init / Run，并晚于自身 Close
+	Depends []string
+
+	// 各个阶段的执行函数，为空时跳过这个阶段
+	Init  func(ctx context.Context) error
+	Run   func(ctx context.Context) error
+	Close func(ctx context.Context) error
+
+	// 健康检查，为空时视为存活并且就绪
+	Health func(ctx context.Context) module.HealthStatus
+}
+
+// LifecycleTimeouts 各个阶段的超时时间，为 0 时只受传入的 context 约束
+type LifecycleTimeouts struct {
+	Init  time.Duration
+	Run   time.Duration
+	Close time.Duration
+}
+
+// ComponentError 组件在某个阶段返回的错误
+type ComponentError struct {
+	Name  string
+	Phase string
+	Err   error
+
+	// Init / Run 失败后回滚（Close）时产生的错误
+	Rollback CloseErrors
+}
+
+func (e *ComponentError) Error() string {
+	if len(e.Rollback) != 0 {
+		return fmt.Sprintf("%v %v err : %v (rollback : %v)", e.Name, e.Phase, e.Err, e.Rollback.Error())
+	}
+	return fmt.Sprintf("%v %v err : %v", e.Name, e.Phase, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// CloseErrors Close 阶段所有组件返回的错误（Close 不会因为某个组件出错而中断
+type CloseErrors []*ComponentError
+
+func (errs CloseErrors) Error() string {
+	lst := make([]string, 0, len(errs))
+	for _, err := range errs {
+		lst = append(lst, err.Error())
+	}
+	return strings.Join(lst, "; ")
+}
+
+// Errors 所有组件的错误（*ComponentError
+func (errs CloseErrors) Errors() []error {
+	lst := make([]error, 0, len(errs))
+	for _, err := range errs {
+		lst = append(lst, err)
+	}
+	return lst
+}
+
+func (errs CloseErrors) Unwrap() []error {
+	return errs.Errors()
+}
+
+// Is 任意一个组件的错误匹配 target 时返回 true（go1.20 之前 errors.Is 不会展开 Unwrap() []error
+func (errs CloseErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 将第一个匹配 target 类型的组件错误赋值给 target
+func (errs CloseErrors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lifecycle 按照组件之间的依赖关系执行 Init / Run / Close
+//
+//	Init 或 Run 失败时，会按照相反的顺序关闭已经初始化的组件
+type Lifecycle struct {
+	timeouts LifecycleTimeouts
+
+	components []*LifecycleComponent
+	names      map[string]*LifecycleComponent
+
+	// 已经 Init 成功的组件（按执行顺序
+	inited []*LifecycleComponent
+	// 是否已经 Run 成功
+	running bool
+
+	sync.Mutex
+}
+
+// NewLifecycle 创建一个生命周期管理器
+func NewLifecycle(timeouts LifecycleTimeouts) *Lifecycle {
+	return &Lifecycle{
+		timeouts: timeouts,
+		names:    make(map[string]*LifecycleComponent),
+	}
+}
+
+// Register 注册一个组件，组件的名称不能重复
+func (l *Lifecycle) Register(c LifecycleComponent) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if c.Name == "" {
+		return fmt.Errorf("component name is empty")
+	}
+
+	if _, ok := l.names[c.Name]; ok {
+		return fmt.Errorf("component %v already registered", c.Name)
+	}
+
+	l.components = append(l.components, &c)
+	l.names[c.Name] = &c
+
+	return nil
+}
+
+// Order 获取组件的执行顺序（依赖的组件在前，没有依赖关系的组件保持注册顺序
+func (l *Lifecycle) Order() ([]string, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	order, err := l.order()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(order))
+	for _, c := range order {
+		names = append(names, c.Name)
+	}
+
+	return names, nil
+}
+
+func (l *Lifecycle) order() ([]*LifecycleComponent, error) {
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int)
+	order := make([]*LifecycleComponent, 0, len(l.components))
+
+	var visit func(c *LifecycleComponent, path []string) error
+	visit = func(c *LifecycleComponent, path []string) error {
+		switch state[c.Name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("component dependency cycle %v", strings.Join(append(path, c.Name), " -> "))
+		}
+
+		state[c.Name] = visiting
+		for _, dep := range c.Depends {
+			dc, ok := l.names[dep]
+			if !ok {
+				return fmt.Errorf("component %v depends on unknown component %v", c.Name, dep)
+			}
+
+			err := visit(dc, append(path, c.Name))
+			if err != nil {
+				return err
+			}
+		}
+		state[c.Name] = visited
+
+		order = append(order, c)
+		return nil
+	}
+
+	for _, c := range l.components {
+		err := visit(c, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// call 在阶段的 context 内执行函数，context 超时后不再等待函数返回
+//
+//	超时时返回的 pending 会在函数真正返回后收到结果，用于在回滚之前等待函数结束
+func call(ctx context.Context, fn func(ctx context.Context) error) (<-chan error, error) {
+
+	done := make(chan error, 1)
+	go func() {
+		done <- protect(ctx, fn)
+	}()
+
+	select {
+	case err := <-done:
+		return nil, err
+	case <-ctx.Done():
+		return done, ctx.Err()
+	}
+}
+
+// protect 执行 fn，fn 中的 panic 作为错误返回
+func protect(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			err = fmt.Errorf("panic %v", perr)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Init 按照依赖顺序初始化组件，失败时按照相反的顺序关闭已经初始化的组件
+//
+//	已经初始化的组件不会被重复初始化（重复调用 Init 只会初始化新注册的组件
+func (l *Lifecycle) Init(ctx context.Context) error {
+	l.Lock()
+	defer l.Unlock()
+
+	order, err := l.order()
+	if err != nil {
+		return err
+	}
+
+	pctx, cancel := withTimeout(ctx, l.timeouts.Init)
+	defer cancel()
+
+	for _, c := range order {
+		if l.isInited(c) {
+			continue
+		}
+
+		if c.Init != nil {
+			var pending <-chan error
+			err = pctx.Err()
+			if err == nil {
+				pending, err = call(pctx, c.Init)
+			}
+			if err != nil {
+				return &ComponentError{Name: c.Name, Phase: PhaseInit, Err: err, Rollback: l.rollback(pending)}
+			}
+		}
+
+		l.inited = append(l.inited, c)
+	}
+
+	return nil
+}
+
+// Run 按照依赖顺序运行组件，失败时按照相反的顺序关闭所有已经初始化的组件
+func (l *Lifecycle) Run(ctx context.Context) error {
+	l.Lock()
+	defer l.Unlock()
+
+	pctx, cancel := withTimeout(ctx, l.timeouts.Run)
+	defer cancel()
+
+	for _, c := range l.inited {
+		if c.Run == nil {
+			continue
+		}
+
+		var pending <-chan error
+		err := pctx.Err()
+		if err == nil {
+			pending, err = call(pctx, c.Run)
+		}
+		if err != nil {
+			return &ComponentError{Name: c.Name, Phase: PhaseRun, Err: err, Rollback: l.rollback(pending)}
+		}
+	}
+
+	l.running = true
+	return nil
+}
+
+// Close 按照相反的顺序关闭已经初始化的组件，返回的错误类型为 CloseErrors
+func (l *Lifecycle) Close(ctx context.Context) error {
+	l.Lock()
+	defer l.Unlock()
+
+	errs := l.close(ctx)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (l *Lifecycle) isInited(c *LifecycleComponent) bool {
+	for _, ic := range l.inited {
+		if ic == c {
+			return true
+		}
+	}
+	return false
+}
+
+// rollback 关闭已经初始化的组件，pending 不为空时（Init / Run 超时）先等待超时的函数返回，
+// 避免在它还在使用其他组件时关闭这些组件（最多等待 Close 阶段的超时时间
+func (l *Lifecycle) rollback(pending <-chan error) CloseErrors {
+
+	if pending != nil {
+		ctx, cancel := withTimeout(context.Background(), l.timeouts.Close)
+		select {
+		case <-pending:
+		case <-ctx.Done():
+		}
+		cancel()
+	}
+
+	// 回滚时传入的 context 可能已经超时，因此只受 Close 阶段的超时时间约束（Close 超时时间 * 组件数量
+	return l.close(context.Background())
+}
+
+// close 按照相反的顺序逐个关闭组件，上一个组件的 Close 返回之后才会关闭下一个组件
+//
+//	每个组件的 Close 收到的 context 带有 LifecycleTimeouts.Close 的超时时间（只用于通知组件尽快结束
+//	等待受传入的 context 约束（最多等待 LifecycleTimeouts.Close * 组件数量），超过截止时间后直接返回，
+//	剩余的组件仍然会在后台按照顺序关闭，返回的错误中包含这些组件
+func (l *Lifecycle) close(ctx context.Context) CloseErrors {
+
+	var closers []*LifecycleComponent
+	for i := len(l.inited) - 1; i >= 0; i-- {
+		if l.inited[i].Close != nil {
+			closers = append(closers, l.inited[i])
+		}
+	}
+
+	l.inited = nil
+	l.running = false
+
+	if len(closers) == 0 {
+		return nil
+	}
+
+	wctx, wcancel := withTimeout(ctx, l.timeouts.Close*time.Duration(len(closers)))
+	defer wcancel()
+
+	results := make(chan error, len(closers))
+	go func() {
+		for _, c := range closers {
+			cctx, cancel := withTimeout(ctx, l.timeouts.Close)
+			results <- protect(cctx, c.Close)
+			cancel()
+		}
+	}()
+
+	var errs CloseErrors
+	for i, c := range closers {
+		select {
+		case err := <-results:
+			if err != nil {
+				errs = append(errs, &ComponentError{Name: c.Name, Phase: PhaseClose, Err: err})
+			}
+		case <-wctx.Done():
+			for _, rc := range closers[i:] {
+				errs = append(errs, &ComponentError{Name: rc.Name, Phase: PhaseClose, Err: wctx.Err()})
+			}
+			return errs
+		}
+	}
+
+	return errs
+}
+
+// HealthReport 所有组件的健康状态
+type HealthReport struct {
+	// 所有组件都存活
+	Live bool `json:"live"`
+
+	// 已经 Run 成功，并且所有组件都就绪
+	Ready bool `json:"ready"`
+
+	// 组件名称 : 健康状态（只包含已经初始化的组件
+	Components map[string]module.HealthStatus `json:"components"`
+}
+
+// Health 并发的检查所有已经初始化的组件，ctx 结束时还没有返回的组件视为未就绪
+func (l *Lifecycle) Health(ctx context.Context) HealthReport {
+	l.Lock()
+	inited := append([]*LifecycleComponent{}, l.inited...)
+	running := l.running
+	l.Unlock()
+
+	type result struct {
+		name   string
+		status module.HealthStatus
+	}
+
+	results := make(chan result, len(inited))
+	checks := 0
+
+	report := HealthReport{
+		Live:       true,
+		Ready:      running,
+		Components: make(map[string]module.HealthStatus),
+	}
+
+	for _, c := range inited {
+		if c.Health == nil {
+			report.Components[c.Name] = module.HealthStatus{Live: true, Ready: true}
+			continue
+		}
+
+		checks++
+		go func(c *LifecycleComponent) {
+			defer func() {
+				if err := recover(); err != nil {
+					results <- result{c.Name, module.HealthStatus{Live: true, Message: fmt.Sprintf("health check panic %v", err)}}
+				}
+			}()
+
+			results <- result{c.Name, c.Health(ctx)}
+		}(c)
+	}
+
+collect:
+	for i := 0; i < checks; i++ {
+		select {
+		case r := <-results:
+			report.Components[r.name] = r.status
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	for _, c := range inited {
+		if _, ok := report.Components[c.Name]; !ok {
+			report.Components[c.Name] = module.HealthStatus{Live: true, Message: "health check " + ctx.Err().Error()}
+		}
+	}
+
+	for _, status := range report.Components {
+		report.Live = report.Live && status.Live
+		report.Ready = report.Ready && status.Ready
+	}
+
+	return report
+}
@@ -0,0 +1,321 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/module"
+	"github.com/stretchr/testify/assert"
+)
+
+type lifecycleRecorder struct {
+	calls []string
+
+	sync.Mutex
+}
+
+func (r *lifecycleRecorder) record(call string) {
+	r.Lock()
+	r.calls = append(r.calls, call)
+	r.Unlock()
+}
+
+func (r *lifecycleRecorder) get() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string{}, r.calls...)
+}
+
+func (r *lifecycleRecorder) component(name string, depends []string, initErr, closeErr error) LifecycleComponent {
+	return LifecycleComponent{
+		Name:    name,
+		Depends: depends,
+		Init: func(ctx context.Context) error {
+			r.record(name + ".init")
+			return initErr
+		},
+		Run: func(ctx context.Context) error {
+			r.record(name + ".run")
+			return nil
+		},
+		Close: func(ctx context.Context) error {
+			r.record(name + ".close")
+			return closeErr
+		},
+	}
+}
+
+func TestLifecycleOrder(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+	l := NewLifecycle(LifecycleTimeouts{})
+
+	assert.Nil(t, l.Register(r.component("client", []string{"balancer", "pubsub"}, nil, nil)))
+	assert.Nil(t, l.Register(r.component("balancer", []string{"pubsub"}, nil, nil)))
+	assert.Nil(t, l.Register(r.component("pubsub", nil, nil, nil)))
+	assert.NotNil(t, l.Register(r.component("pubsub", nil, nil, nil)))
+
+	order, err := l.Order()
+	assert.Nil(t, err)
+	assert.Equal(t, order, []string{"pubsub", "balancer", "client"})
+
+	assert.Nil(t, l.Init(context.TODO()))
+	assert.Nil(t, l.Run(context.TODO()))
+	assert.Nil(t, l.Close(context.TODO()))
+
+	assert.Equal(t, r.calls, []string{
+		"pubsub.init", "balancer.init", "client.init",
+		"pubsub.run", "balancer.run", "client.run",
+		"client.close", "balancer.close", "pubsub.close",
+	})
+}
+
+func TestLifecycleDependsErr(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+
+	l := NewLifecycle(LifecycleTimeouts{})
+	l.Register(r.component("a", []string{"b"}, nil, nil))
+	l.Register(r.component("b", []string{"a"}, nil, nil))
+	assert.NotNil(t, l.Init(context.TODO()))
+
+	l = NewLifecycle(LifecycleTimeouts{})
+	l.Register(r.component("a", []string{"unknown"}, nil, nil))
+	assert.NotNil(t, l.Init(context.TODO()))
+
+	assert.Equal(t, len(r.calls), 0)
+}
+
+func TestLifecycleRollback(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+	l := NewLifecycle(LifecycleTimeouts{})
+
+	initErr := errors.New("init failed")
+	closeErr := errors.New("close failed")
+
+	l.Register(r.component("pubsub", nil, nil, nil))
+	l.Register(r.component("balancer", []string{"pubsub"}, nil, closeErr))
+	l.Register(r.component("client", []string{"balancer"}, initErr, nil))
+
+	err := l.Init(context.TODO())
+	assert.True(t, errors.Is(err, initErr))
+
+	var cerr *ComponentError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, cerr.Name, "client")
+	assert.Equal(t, cerr.Phase, PhaseInit)
+	assert.Equal(t, len(cerr.Rollback), 1)
+	assert.Equal(t, cerr.Rollback[0].Name, "balancer")
+
+	// 初始化失败的组件不会被关闭
+	assert.Equal(t, r.calls, []string{
+		"pubsub.init", "balancer.init", "client.init",
+		"balancer.close", "pubsub.close",
+	})
+
+	// 已经回滚的组件不会被重复关闭
+	assert.Nil(t, l.Close(context.TODO()))
+}
+
+func TestLifecycleTimeout(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+	l := NewLifecycle(LifecycleTimeouts{Init: time.Millisecond * 20})
+
+	l.Register(r.component("pubsub", nil, nil, nil))
+	l.Register(LifecycleComponent{
+		Name:    "slow",
+		Depends: []string{"pubsub"},
+		Init: func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 100)
+			r.record("slow.init")
+			return nil
+		},
+	})
+
+	// 回滚前等待超时的 Init 返回，避免 Close 与 Init 同时执行
+	err := l.Init(context.TODO())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, r.get(), []string{"pubsub.init", "slow.init", "pubsub.close"})
+}
+
+func TestLifecycleInitTwice(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+	l := NewLifecycle(LifecycleTimeouts{})
+
+	l.Register(r.component("pubsub", nil, nil, nil))
+	assert.Nil(t, l.Init(context.TODO()))
+	assert.Nil(t, l.Init(context.TODO()))
+
+	// 只有新注册的组件会被初始化
+	l.Register(r.component("client", []string{"pubsub"}, nil, nil))
+	assert.Nil(t, l.Init(context.TODO()))
+	assert.Nil(t, l.Close(context.TODO()))
+
+	assert.Equal(t, r.get(), []string{"pubsub.init", "client.init", "client.close", "pubsub.close"})
+}
+
+func TestLifecycleCloseTimeout(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+	l := NewLifecycle(LifecycleTimeouts{Close: time.Millisecond * 50})
+
+	slow := func(d time.Duration) LifecycleComponent {
+		return LifecycleComponent{
+			Name:    "slow",
+			Depends: []string{"pubsub"},
+			Close: func(ctx context.Context) error {
+				time.Sleep(d)
+				r.record("slow.close")
+				return nil
+			},
+		}
+	}
+
+	l.Register(r.component("pubsub", nil, nil, nil))
+	l.Register(slow(time.Millisecond * 60))
+	l.Register(r.component("client", []string{"slow"}, nil, nil))
+
+	// 超过自身超时时间的组件仍然会被等待（在总的截止时间内），之后的组件在它返回之后才会关闭
+	assert.Nil(t, l.Init(context.TODO()))
+	assert.Nil(t, l.Close(context.TODO()))
+	assert.Equal(t, r.get(), []string{"pubsub.init", "client.init", "client.close", "slow.close", "pubsub.close"})
+
+	// 超过总的截止时间（Close 超时时间 * 组件数量）后不再等待，剩余的组件在后台按照顺序关闭
+	l = NewLifecycle(LifecycleTimeouts{Close: time.Millisecond * 20})
+	r = &lifecycleRecorder{}
+	l.Register(r.component("pubsub", nil, nil, nil))
+	l.Register(slow(time.Millisecond * 150))
+	l.Register(r.component("client", []string{"slow"}, nil, nil))
+
+	assert.Nil(t, l.Init(context.TODO()))
+	begin := time.Now()
+	err := l.Close(context.TODO())
+	assert.Less(t, time.Since(begin), time.Millisecond*120)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	var errs CloseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 2)
+	assert.Equal(t, errs[0].Name, "slow")
+	assert.Equal(t, errs[1].Name, "pubsub")
+	assert.Equal(t, r.get(), []string{"pubsub.init", "client.init", "client.close"})
+
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 5
+	}, time.Second, time.Millisecond*10)
+	assert.Equal(t, r.get()[3:], []string{"slow.close", "pubsub.close"})
+
+	// context 已经结束时仍然会按照顺序调用所有组件的 Close
+	r.Lock()
+	r.calls = nil
+	r.Unlock()
+	assert.Nil(t, l.Init(context.TODO()))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	assert.NotNil(t, l.Close(ctx))
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 5
+	}, time.Second, time.Millisecond*10)
+	assert.Equal(t, r.get()[2:], []string{"client.close", "slow.close", "pubsub.close"})
+}
+
+func TestLifecycleCloseErrors(t *testing.T) {
+
+	r := &lifecycleRecorder{}
+	l := NewLifecycle(LifecycleTimeouts{})
+
+	errA := errors.New("a close failed")
+	errB := errors.New("b close failed")
+
+	l.Register(r.component("a", nil, nil, errA))
+	l.Register(r.component("b", []string{"a"}, nil, errB))
+
+	assert.Nil(t, l.Init(context.TODO()))
+	assert.Nil(t, l.Run(context.TODO()))
+
+	err := l.Close(context.TODO())
+
+	var errs CloseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 2)
+	assert.Equal(t, errs[0].Name, "b")
+	assert.Equal(t, errs[1].Name, "a")
+	assert.True(t, errors.Is(err, errA))
+	assert.True(t, errors.Is(err, errB))
+	assert.Equal(t, errs.Errors(), []error{errs[0], errs[1]})
+
+	// 不依赖 go1.20 的 Unwrap() []error
+	assert.True(t, errs.Is(errA))
+	assert.False(t, errs.Is(context.Canceled))
+
+	var cerr *ComponentError
+	assert.True(t, errs.As(&cerr))
+	assert.Equal(t, cerr.Name, "b")
+}
+
+func TestLifecycleHealth(t *testing.T) {
+
+	l := NewLifecycle(LifecycleTimeouts{})
+
+	ready := true
+	l.Register(LifecycleComponent{
+		Name: "pubsub",
+	})
+	l.Register(LifecycleComponent{
+		Name:    "discover",
+		Depends: []string{"pubsub"},
+		Health: func(ctx context.Context) module.HealthStatus {
+			return module.HealthStatus{Live: true, Ready: ready}
+		},
+	})
+	l.Register(LifecycleComponent{
+		Name:    "slow",
+		Depends: []string{"pubsub"},
+		Health: func(ctx context.Context) module.HealthStatus {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			return module.HealthStatus{Live: true, Ready: true}
+		},
+	})
+
+	// 还没有初始化
+	report := l.Health(context.TODO())
+	assert.True(t, report.Live)
+	assert.False(t, report.Ready)
+	assert.Equal(t, len(report.Components), 0)
+
+	assert.Nil(t, l.Init(context.TODO()))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond*20)
+	defer cancel()
+
+	// 初始化完成，但是还没有运行
+	report = l.Health(ctx)
+	assert.True(t, report.Live)
+	assert.False(t, report.Ready)
+	assert.Equal(t, len(report.Components), 3)
+	assert.True(t, report.Components["discover"].Ready)
+	assert.False(t, report.Components["slow"].Ready)
+
+	assert.Nil(t, l.Run(context.TODO()))
+
+	report = l.Health(context.TODO())
+	assert.True(t, report.Ready)
+
+	ready = false
+	report = l.Health(context.TODO())
+	assert.True(t, report.Live)
+	assert.False(t, report.Ready)
+	assert.False(t, report.Components["discover"].Ready)
+
+	l.Close(context.TODO())
+	assert.False(t, l.Health(context.TODO()).Ready)
+}
@@ -0,0 +1,206 @@
+// 实现文件 基于进程内存实现的链路缓存，使用同一个 Store 的同名服务实例共享链路信息
+package linkcachememory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+const (
+	splitFlag = "-"
+)
+
+type linkInfo struct {
+	TargetAddr string
+	TargetID   string
+	TargetName string
+	Token      string
+}
+
+// Store 链路信息，使用同一个 Store 的同名服务共享链路
+type Store struct {
+	sync.RWMutex
+
+	// parent service name : ( target service name - token : link info )
+	links map[string]map[string]linkInfo
+}
+
+// NewStore 创建一个链路信息容器
+func NewStore() *Store {
+	return &Store{
+		links: make(map[string]map[string]linkInfo),
+	}
+}
+
+var globalStore = NewStore()
+
+// GlobalStore 进程内全局共享的链路信息（需要通过 WithStore 显式使用
+func GlobalStore() *Store {
+	return globalStore
+}
+
+type memoryLinker struct {
+	info meta.ServiceInfo
+	parm Parm
+
+	ps  module.IPubsub
+	log *blog.Logger
+	s   *Store
+
+	tokenUnlink   module.IChannel
+	serviceUpdate module.IChannel
+}
+
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, ps module.IPubsub, opts ...Option) module.ILinkCache {
+
+	p := Parm{}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if p.Store == nil {
+		p.Store = NewStore()
+	}
+
+	return &memoryLinker{
+		info: info,
+		parm: p,
+		ps:   ps,
+		log:  log,
+		s:    p.Store,
+	}
+}
+
+func (ml *memoryLinker) Init() error {
+	var err error
+
+	ml.tokenUnlink, err = ml.ps.GetTopic(meta.TopicLinkcacheUnlink).
+		Sub(context.TODO(), meta.ModuleLink+"-"+ml.info.ID)
+	if err != nil {
+		return err
+	}
+	ml.serviceUpdate, err = ml.ps.GetTopic(meta.TopicDiscoverServiceUpdate).
+		Sub(context.TODO(), meta.ModuleLink+"-"+ml.info.ID)
+	if err != nil {
+		return err
+	}
+
+	ml.tokenUnlink.Arrived(func(msg *meta.Message) error {
+		token := string(msg.Body)
+		if token != "" && token != "nil" {
+			ml.Unlink(token)
+		}
+		return nil
+	})
+
+	ml.serviceUpdate.Arrived(func(msg *meta.Message) error {
+		dmsg := meta.DecodeUpdateMsg(msg)
+		if dmsg.Event == meta.TopicDiscoverServiceNodeRmv {
+			ml.Down(dmsg.Nod)
+		}
+		return nil
+	})
+
+	return nil
+}
+
+func (ml *memoryLinker) Run() {
+
+}
+
+func (ml *memoryLinker) Target(token string, serviceName string) (string, error) {
+	ml.s.RLock()
+	defer ml.s.RUnlock()
+
+	info, ok := ml.s.links[ml.info.Name][serviceName+splitFlag+token]
+	if !ok {
+		return "", fmt.Errorf("can't find token by service %v", serviceName)
+	}
+
+	return info.TargetAddr, nil
+}
+
+func (ml *memoryLinker) Link(token string, target meta.Node) error {
+	ml.s.Lock()
+	defer ml.s.Unlock()
+
+	if _, ok := ml.s.links[ml.info.Name]; !ok {
+		ml.s.links[ml.info.Name] = make(map[string]linkInfo)
+	}
+
+	key := target.Name + splitFlag + token
+	if _, ok := ml.s.links[ml.info.Name][key]; !ok {
+		ml.s.links[ml.info.Name][key] = linkInfo{
+			TargetAddr: target.Address,
+			TargetID:   target.ID,
+			TargetName: target.Name,
+			Token:      token,
+		}
+	}
+
+	return nil
+}
+
+// Unlink 解除 token 和所有目标服务之间的绑定关系
+func (ml *memoryLinker) Unlink(token string) error {
+	ml.s.Lock()
+
+	var unlinked bool
+	for key, info := range ml.s.links[ml.info.Name] {
+		if info.Token == token {
+			delete(ml.s.links[ml.info.Name], key)
+			unlinked = true
+		}
+	}
+
+	ml.s.Unlock()
+
+	if unlinked {
+		ml.unlinked(token)
+	}
+
+	return nil
+}
+
+// Down 删除离线节点的链路缓存
+func (ml *memoryLinker) Down(target meta.Node) error {
+	ml.s.Lock()
+
+	var tokens []string
+	for key, info := range ml.s.links[ml.info.Name] {
+		if info.TargetID == target.ID {
+			delete(ml.s.links[ml.info.Name], key)
+			tokens = append(tokens, info.Token)
+		}
+	}
+
+	ml.s.Unlock()
+
+	if len(tokens) != 0 {
+		ml.log.Debugf("[braid.linkcache] down service %s node %s tokens %d", target.Name, target.ID, len(tokens))
+		ml.unlinked(tokens...)
+	}
+
+	return nil
+}
+
+// unlinked 通知 token 的链路信息已经被移除
+func (ml *memoryLinker) unlinked(tokens ...string) {
+	for _, token := range tokens {
+		err := ml.ps.GetTopic(meta.TopicLinkcacheUnlinked).Pub(context.TODO(), &meta.Message{Body: []byte(token)})
+		if err != nil {
+			ml.log.Warnf("[braid.linkcache] pub unlinked token %s err %s", token, err.Error())
+		}
+	}
+}
+
+func (ml *memoryLinker) Close() {
+	ml.serviceUpdate.Close()
+	ml.tokenUnlink.Close()
+}
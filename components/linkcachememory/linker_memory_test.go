@@ -0,0 +1,73 @@
+package linkcachememory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkerTarget(t *testing.T) {
+
+	log := blog.BuildWithOption()
+	ps := pubsubmemory.BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, log)
+
+	s := NewStore()
+	lc := BuildWithOption(meta.ServiceInfo{ID: "gate-1", Name: "mem-gate"}, log, ps, WithStore(s))
+	// 同名服务的其他实例共享链路信息
+	lc2 := BuildWithOption(meta.ServiceInfo{ID: "gate-2", Name: "mem-gate"}, log, ps, WithStore(s))
+
+	lc.Init()
+	lc.Run()
+	defer lc.Close()
+
+	unlinked, err := ps.GetTopic(meta.TopicLinkcacheUnlinked).Sub(context.TODO(), "TestLinkerTarget")
+	assert.Equal(t, err, nil)
+	defer unlinked.Close()
+
+	tokens := make(chan string, 10)
+	unlinked.Arrived(func(msg *meta.Message) error {
+		tokens <- string(msg.Body)
+		return nil
+	})
+
+	nods := []meta.Node{
+		{ID: "a001", Name: "base", Address: "127.0.0.1:12001"},
+		{ID: "a002", Name: "login", Address: "127.0.0.1:13001"},
+	}
+
+	assert.Equal(t, lc.Link("token01", nods[0]), nil)
+	assert.Equal(t, lc.Link("token01", nods[1]), nil)
+	assert.Equal(t, lc.Link("token02", nods[0]), nil)
+
+	addr, err := lc2.Target("token01", "base")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, addr, "127.0.0.1:12001")
+
+	_, err = lc.Target("unknowtoken", "base")
+	assert.NotEqual(t, err, nil)
+
+	ps.GetTopic(meta.TopicLinkcacheUnlink).Pub(context.TODO(), &meta.Message{Body: []byte("token01")})
+	time.Sleep(time.Millisecond * 100)
+
+	_, err = lc.Target("token01", "login")
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, <-tokens, "token01")
+
+	ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(),
+		meta.EncodeUpdateMsg(meta.TopicDiscoverServiceNodeRmv, nods[0]))
+	time.Sleep(time.Millisecond * 100)
+
+	_, err = lc.Target("token02", "base")
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, <-tokens, "token02")
+
+	// 没有链路信息的 token 不会通知
+	ps.GetTopic(meta.TopicLinkcacheUnlink).Pub(context.TODO(), &meta.Message{Body: []byte("token03")})
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, len(tokens), 0)
+}
@@ -0,0 +1,17 @@
+package linkcachememory
+
+// Parm linkcache 配置
+type Parm struct {
+	// 共享的链路信息，为空时每个实例单独创建
+	Store *Store
+}
+
+// Option config wraps
+type Option func(*Parm)
+
+// WithStore 设置共享的链路信息（NewStore or GlobalStore
+func WithStore(s *Store) Option {
+	return func(c *Parm) {
+		c.Store = s
+	}
+}
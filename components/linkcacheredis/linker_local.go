@@ -3,6 +3,7 @@ package linkcacheredis
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pojol/braid-go/module/meta"
 )
@@ -56,18 +57,23 @@ func (ll *localLinker) unlink(token string, target string) linkInfo {
 	return info
 }
 
-func (ll *localLinker) down(target meta.Node) int {
+func (ll *localLinker) down(target meta.Node) []string {
 
-	var cnt int
+	var tokens []string
 
-	for key := range ll.tokenMap {
-		if ll.tokenMap[key].TargetID == target.ID {
+	for key, info := range ll.tokenMap {
+		if info.TargetID == target.ID {
 			delete(ll.tokenMap, key)
-			cnt++
+			tokens = append(tokens, ll.token(key, info))
 		}
 	}
 
-	return cnt
+	return tokens
+}
+
+// token 从缓存的 key 中取出 token
+func (ll *localLinker) token(key string, info linkInfo) string {
+	return strings.TrimPrefix(key, ll.serviceName+splitFlag+info.TargetName+splitFlag)
 }
 
 func (ll *localLinker) addRelation(relation string) {
@@ -110,20 +116,21 @@ func (rl *redisLinker) localLink(token string, target meta.Node) error {
 	return nil
 }
 
-func (rl *redisLinker) localUnlink(token string, target string) error {
+func (rl *redisLinker) localUnlink(token string, target string) (bool, error) {
 
 	info := rl.local.unlink(token, target)
 
 	if info.TargetID != "" {
 		rl.client.Decr(context.TODO(), rl.getLinkNumKey(info.TargetName, info.TargetID))
+		return true, nil
 	}
 
-	return nil
+	return false, nil
 }
 
-func (rl *redisLinker) localDown(target meta.Node) error {
+func (rl *redisLinker) localDown(target meta.Node) ([]string, error) {
 
-	rl.local.down(target)
+	tokens := rl.local.down(target)
 
 	relationKey := rl.getLinkNumKey(target.Name, target.ID)
 	rl.local.rmvRelation(relationKey)
@@ -131,5 +138,19 @@ func (rl *redisLinker) localDown(target meta.Node) error {
 	rl.client.SRem(context.TODO(), RelationPrefix, relationKey)
 	rl.client.Del(context.TODO(), rl.getLinkNumKey(target.Name, target.ID))
 
-	return nil
+	return tokens, nil
+}
+
+// localUnlinkAll 释放本节点缓存的所有 token（节点退出时调用），返回释放的 token
+func (rl *redisLinker) localUnlinkAll() []string {
+
+	var tokens []string
+
+	for key, info := range rl.local.tokenMap {
+		rl.client.Decr(context.TODO(), rl.getLinkNumKey(info.TargetName, info.TargetID))
+		delete(rl.local.tokenMap, key)
+		tokens = append(tokens, rl.local.token(key, info))
+	}
+
+	return tokens
 }
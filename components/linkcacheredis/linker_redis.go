@@ -93,7 +93,7 @@ func (rl *redisLinker) redisLink(token string, target meta.Node) error {
 	return err
 }
 
-func (rl *redisLinker) redisUnlink(token string, target string) error {
+func (rl *redisLinker) redisUnlink(token string, target string) (bool, error) {
 
 	var cnt uint64
 	var err error
@@ -101,29 +101,31 @@ func (rl *redisLinker) redisUnlink(token string, target string) error {
 
 	info, err = rl.findToken(token, target)
 	if err != nil {
-		return nil
+		return false, nil
 	}
 
 	cnt, err = rl.client.HDel(context.TODO(), RoutePrefix+splitFlag+rl.info.Name+splitFlag+target, token).Uint64()
 	if err == nil && cnt == 1 {
 		rl.client.Decr(context.TODO(), rl.getLinkNumKey(info.TargetName, info.TargetID))
+		return true, nil
 	}
 
-	return nil
+	return false, nil
 }
 
 // todo
-func (rl *redisLinker) redisDown(target meta.Node) error {
+func (rl *redisLinker) redisDown(target meta.Node) ([]string, error) {
 
 	var info *linkInfo
 	var cnt uint64
+	var tokens []string
 
 	routekey := RoutePrefix + splitFlag + rl.info.Name + splitFlag + target.Name
 	ctx := context.TODO()
 
 	tokenMap, err := rl.client.HGetAll(ctx, routekey).Result()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for key := range tokenMap {
@@ -137,6 +139,9 @@ func (rl *redisLinker) redisDown(target meta.Node) error {
 		if info.TargetID == target.ID {
 			rmcnt, _ := rl.client.HDel(ctx, routekey, key).Uint64()
 			cnt += rmcnt
+			if rmcnt == 1 {
+				tokens = append(tokens, key)
+			}
 		}
 
 	}
@@ -147,5 +152,5 @@ func (rl *redisLinker) redisDown(target meta.Node) error {
 	rl.client.SRem(ctx, RelationPrefix, relationKey)
 	rl.client.Del(ctx, relationKey)
 
-	return nil
+	return tokens, nil
 }
@@ -230,9 +230,9 @@ func (rl *redisLinker) syncOffline(ctx context.Context) {
 	}
 
 	rl.Lock()
-	defer rl.Unlock()
 
 	offline := []meta.Node{}
+	unlinked := []string{}
 
 	for _, member := range members {
 		info := strings.Split(member, splitFlag)
@@ -255,17 +255,23 @@ func (rl *redisLinker) syncOffline(ctx context.Context) {
 	}
 
 	for _, service := range offline {
+		var tokens []string
 		if rl.parm.Mode == LinkerRedisModeLocal {
-			err = rl.localDown(service)
+			tokens, err = rl.localDown(service)
 		} else if rl.parm.Mode == LinkerRedisModeRedis {
-			err = rl.redisDown(service)
+			tokens, err = rl.redisDown(service)
 		}
+		unlinked = append(unlinked, tokens...)
 
 		rl.log.Debugf("offline service mode:%v, name:%v, id:%v", rl.parm.Mode, service.Name, service.ID)
 		if err != nil {
 			rl.log.Warnf("offline err %v", err.Error())
 		}
 	}
+
+	rl.Unlock()
+
+	rl.unlinked(unlinked...)
 }
 
 func (rl *redisLinker) Run() {
@@ -351,17 +357,24 @@ func (rl *redisLinker) Link(token string, target meta.Node) error {
 func (rl *redisLinker) Unlink(token string) error {
 
 	rl.Lock()
-	defer rl.Unlock()
 
 	var err error
+	var unlinked, ok bool
 
 	// 尝试将自身名下的节点中的token释放掉
 	for _, child := range rl.child {
 		if rl.parm.Mode == LinkerRedisModeRedis && atomic.LoadInt32(&rl.electorState) == meta.EMaster {
-			err = rl.redisUnlink(token, child)
+			ok, err = rl.redisUnlink(token, child)
 		} else if rl.parm.Mode == LinkerRedisModeLocal {
-			err = rl.localUnlink(token, child)
+			ok, err = rl.localUnlink(token, child)
 		}
+		unlinked = unlinked || ok
+	}
+
+	rl.Unlock()
+
+	if unlinked {
+		rl.unlinked(token)
 	}
 
 	return err
@@ -371,21 +384,55 @@ func (rl *redisLinker) Unlink(token string) error {
 func (rl *redisLinker) Down(target meta.Node) error {
 
 	rl.Lock()
-	defer rl.Unlock()
 
 	var err error
+	var tokens []string
 
 	if rl.parm.Mode == LinkerRedisModeRedis && atomic.LoadInt32(&rl.electorState) == meta.EMaster {
-		err = rl.redisDown(target)
+		tokens, err = rl.redisDown(target)
 	} else if rl.parm.Mode == LinkerRedisModeLocal {
-		err = rl.localDown(target)
+		tokens, err = rl.localDown(target)
 	}
 
+	rl.Unlock()
+
+	rl.unlinked(tokens...)
+
 	return err
 }
 
+// unlinked 通知 token 的链路信息已经被移除
+func (rl *redisLinker) unlinked(tokens ...string) {
+	for _, token := range tokens {
+		err := rl.ps.GetTopic(meta.TopicLinkcacheUnlinked).Pub(context.TODO(), &meta.Message{Body: []byte(token)})
+		if err != nil {
+			rl.log.Warnf("[braid.linkcache] pub unlinked token %s err %s", token, err.Error())
+		}
+	}
+}
+
+// Health 检查 redis 是否可以访问
+func (rl *redisLinker) Health(ctx context.Context) module.HealthStatus {
+	err := rl.client.Ping(ctx).Err()
+	if err != nil {
+		return module.HealthStatus{Live: true, Message: "redis ping err " + err.Error()}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true}
+}
+
 func (rl *redisLinker) Close() {
 	rl.changeState.Close()
 	rl.serviceUpdate.Close()
 	rl.tokenUnlink.Close()
+
+	// local 模式下 token 只缓存在本节点中，退出时需要释放
+	if rl.parm.Mode == LinkerRedisModeLocal {
+		rl.Lock()
+		tokens := rl.localUnlinkAll()
+		rl.Unlock()
+
+		rl.log.Infof("[braid.linkcache] unlink local tokens %d", len(tokens))
+		rl.unlinked(tokens...)
+	}
 }
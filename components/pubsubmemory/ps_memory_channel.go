@@ -0,0 +1,113 @@
+package pubsubmemory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pojol/braid-go/components/internal/buffer"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// memoryChannel topic 中的消费队列，消息会被轮流派发给 channel 中的消费者
+type memoryChannel struct {
+	sync.Mutex
+
+	name  string
+	topic *memoryTopic
+
+	consumers []*memoryConsumer
+	idx       int
+}
+
+type memoryConsumer struct {
+	c *memoryChannel
+
+	msgCh *buffer.UnboundedMsg
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newChannel(topic *memoryTopic, name string) *memoryChannel {
+	return &memoryChannel{
+		name:  name,
+		topic: topic,
+	}
+}
+
+func (c *memoryChannel) addConsumer() *memoryConsumer {
+	consumer := &memoryConsumer{
+		c:     c,
+		msgCh: buffer.NewUUnboundedMsg(),
+		done:  make(chan struct{}),
+	}
+
+	c.Lock()
+	c.consumers = append(c.consumers, consumer)
+	c.Unlock()
+
+	return consumer
+}
+
+func (c *memoryChannel) put(msg *meta.Message) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.consumers) == 0 {
+		return
+	}
+
+	c.idx = (c.idx + 1) % len(c.consumers)
+	c.consumers[c.idx].msgCh.Put(msg)
+}
+
+// rmvConsumer 移除消费者，返回 channel 中是否还有剩余的消费者
+func (c *memoryChannel) rmvConsumer(consumer *memoryConsumer) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, v := range c.consumers {
+		if v == consumer {
+			c.consumers = append(c.consumers[:k], c.consumers[k+1:]...)
+			break
+		}
+	}
+
+	return len(c.consumers) != 0
+}
+
+func (mc *memoryConsumer) Arrived(handler module.Handler) {
+	go func() {
+		for {
+			select {
+			case m := <-mc.msgCh.Get():
+				mc.msgCh.Load()
+
+				err := handler(m)
+				if err != nil {
+					mc.c.topic.log.Warnf("[braid.pubsub ]Topic %v channel %v id %v handler err %v",
+						mc.c.topic.topic, mc.c.name, m.ID(), err)
+				}
+			case <-mc.done:
+				return
+			}
+		}
+	}()
+}
+
+func (mc *memoryConsumer) Close() error {
+	closed := false
+
+	mc.once.Do(func() {
+		close(mc.done)
+		closed = true
+
+		mc.c.topic.rmvConsumer(mc)
+	})
+
+	if !closed {
+		return fmt.Errorf("channel %v already closed", mc.c.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// 实现文件 基于进程内存实现的 pubsub
+package pubsubmemory
+
+import (
+	"sync"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// Broker topic 容器，使用同一个 Broker 的 pubsub 实例共享 topic
+type Broker struct {
+	sync.Mutex
+	topicMap map[string]*memoryTopic
+}
+
+// NewBroker 创建一个 topic 容器
+func NewBroker() *Broker {
+	return &Broker{
+		topicMap: make(map[string]*memoryTopic),
+	}
+}
+
+var globalBroker = NewBroker()
+
+// GlobalBroker 进程内全局共享的 topic 容器（需要通过 WithBroker 显式使用
+func GlobalBroker() *Broker {
+	return globalBroker
+}
+
+type memoryPubsub struct {
+	info meta.ServiceInfo
+	parm Parm
+
+	log *blog.Logger
+	b   *Broker
+}
+
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, opts ...Option) module.IPubsub {
+
+	p := Parm{}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if p.Broker == nil {
+		p.Broker = NewBroker()
+	}
+
+	return &memoryPubsub{
+		info: info,
+		parm: p,
+		log:  log,
+		b:    p.Broker,
+	}
+}
+
+func (mps *memoryPubsub) Info() {
+
+}
+
+func (mps *memoryPubsub) GetTopic(name string) module.ITopic {
+
+	mps.b.Lock()
+	defer mps.b.Unlock()
+
+	t, ok := mps.b.topicMap[name]
+	if !ok {
+		t = newTopic(name, mps.b, mps.log)
+		mps.b.topicMap[name] = t
+	}
+
+	return t
+}
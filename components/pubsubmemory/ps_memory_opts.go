@@ -0,0 +1,21 @@
+package pubsubmemory
+
+/*
+	基于进程内存实现的 pubsub，使用同一个 Broker 的实例共享 topic
+	用于单进程部署以及不依赖外部服务的集成测试
+*/
+
+type Parm struct {
+	// 共享的 topic 容器，为空时每个实例单独创建
+	Broker *Broker
+}
+
+// Option config wraps
+type Option func(*Parm)
+
+// WithBroker 设置共享的 topic 容器（NewBroker or GlobalBroker
+func WithBroker(b *Broker) Option {
+	return func(c *Parm) {
+		c.Broker = b
+	}
+}
@@ -0,0 +1,173 @@
+package pubsubmemory
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopic(t *testing.T) {
+
+	ps := BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, blog.BuildWithDefaultOption())
+
+	ctx := context.TODO()
+	topic := ps.GetTopic("test.topic.1")
+	defer topic.Close()
+
+	assert.NotEqual(t, topic.Pub(ctx, nil), nil)
+
+	channel, err := topic.Sub(ctx, "channel.1")
+	assert.Equal(t, err, nil)
+	defer channel.Close()
+
+	recv := make(chan []byte, 1)
+	channel.Arrived(func(msg *meta.Message) error {
+		recv <- msg.Body
+		return nil
+	})
+
+	topic.Pub(ctx, &meta.Message{Body: []byte("test msg")})
+
+	select {
+	case body := <-recv:
+		assert.Equal(t, body, []byte("test msg"))
+	case <-time.After(time.Second):
+		t.Fatal("message not arrived")
+	}
+}
+
+// 使用同一个 Broker 的实例共享 topic
+func TestSharedTopic(t *testing.T) {
+
+	log := blog.BuildWithDefaultOption()
+	b := NewBroker()
+	ps1 := BuildWithOption(meta.ServiceInfo{ID: "1", Name: "a"}, log, WithBroker(b))
+	ps2 := BuildWithOption(meta.ServiceInfo{ID: "2", Name: "b"}, log, WithBroker(b))
+	ps3 := BuildWithOption(meta.ServiceInfo{ID: "3", Name: "c"}, log)
+
+	var tick int32
+
+	channel, _ := ps2.GetTopic("test.topic.shared").Sub(context.TODO(), "channel.1")
+	defer channel.Close()
+	channel.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&tick, 1)
+		return nil
+	})
+
+	ps1.GetTopic("test.topic.shared").Pub(context.TODO(), &meta.Message{Body: []byte("msg")})
+	// 没有共享 Broker 的实例相互隔离
+	ps3.GetTopic("test.topic.shared").Pub(context.TODO(), &meta.Message{Body: []byte("msg")})
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, atomic.LoadInt32(&tick), int32(1))
+}
+
+// 模拟多个消费者 - 广播
+func TestMultiBroadcastConsumer(t *testing.T) {
+
+	ps := BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, blog.BuildWithDefaultOption())
+	var c1, c2 int32
+
+	ctx := context.TODO()
+	topic := ps.GetTopic("test.topic.multi.broadcast")
+	defer topic.Close()
+
+	channel1, _ := topic.Sub(ctx, "channel.1")
+	defer channel1.Close()
+	channel2, _ := topic.Sub(ctx, "channel.2")
+	defer channel2.Close()
+
+	channel1.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&c1, 1)
+		return nil
+	})
+	channel2.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&c2, 1)
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		topic.Pub(ctx, &meta.Message{Body: []byte("msg")})
+	}
+
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, atomic.LoadInt32(&c1), int32(10))
+	assert.Equal(t, atomic.LoadInt32(&c2), int32(10))
+}
+
+// 模拟多个消费者 - 竞争
+func TestMultiRoundConsumer(t *testing.T) {
+
+	ps := BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, blog.BuildWithDefaultOption())
+	var c1, c2 int32
+
+	ctx := context.TODO()
+	topic := ps.GetTopic("test.topic.multi.round")
+	defer topic.Close()
+
+	channel1, _ := topic.Sub(ctx, "channel.1")
+	defer channel1.Close()
+	channel2, _ := topic.Sub(ctx, "channel.1")
+	defer channel2.Close()
+
+	channel1.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&c1, 1)
+		return nil
+	})
+	channel2.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&c2, 1)
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		topic.Pub(ctx, &meta.Message{Body: []byte("msg")})
+	}
+
+	time.Sleep(time.Millisecond * 200)
+	assert.NotEqual(t, atomic.LoadInt32(&c1), int32(0))
+	assert.NotEqual(t, atomic.LoadInt32(&c2), int32(0))
+	assert.Equal(t, atomic.LoadInt32(&c1)+atomic.LoadInt32(&c2), int32(10))
+}
+
+func TestChannelClose(t *testing.T) {
+
+	b := NewBroker()
+	ps := BuildWithOption(meta.ServiceInfo{ID: "id", Name: "name"}, blog.BuildWithDefaultOption(), WithBroker(b))
+	var tick int32
+
+	ctx := context.TODO()
+	topic := ps.GetTopic("test.topic.close")
+
+	channel1, _ := topic.Sub(ctx, "channel.1")
+	channel2, _ := topic.Sub(ctx, "channel.2")
+	channel1.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&tick, 1)
+		return nil
+	})
+	channel2.Arrived(func(msg *meta.Message) error {
+		atomic.AddInt32(&tick, 1)
+		return nil
+	})
+
+	assert.Equal(t, channel1.Close(), nil)
+	assert.NotEqual(t, channel1.Close(), nil)
+
+	topic.Pub(ctx, &meta.Message{Body: []byte("msg")})
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, atomic.LoadInt32(&tick), int32(1))
+
+	channel2.Close()
+	topic.Close()
+
+	mt := topic.(*memoryTopic)
+	assert.Equal(t, len(mt.channelMap), 0)
+	b.Lock()
+	_, ok := b.topicMap["test.topic.close"]
+	b.Unlock()
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,91 @@
+package pubsubmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+type memoryTopic struct {
+	sync.RWMutex
+
+	topic string
+	log   *blog.Logger
+	b     *Broker
+
+	channelMap map[string]*memoryChannel
+}
+
+func newTopic(name string, b *Broker, log *blog.Logger) *memoryTopic {
+	return &memoryTopic{
+		topic:      name,
+		b:          b,
+		log:        log,
+		channelMap: make(map[string]*memoryChannel),
+	}
+}
+
+// Pub 将消息投递到 topic 中的每一个 channel（广播），channel 内部由其中一个消费者接收（竞争
+func (mt *memoryTopic) Pub(ctx context.Context, msg *meta.Message) error {
+
+	if msg == nil {
+		return fmt.Errorf("can't send empty msg to %v", mt.topic)
+	}
+
+	mt.RLock()
+	defer mt.RUnlock()
+
+	for _, c := range mt.channelMap {
+		c.put(meta.CreateMessage(uuid.New().String(), msg.Body))
+	}
+
+	return nil
+}
+
+// Sub 订阅 topic，只会接收到订阅之后发布的消息
+func (mt *memoryTopic) Sub(ctx context.Context, channel string, opts ...interface{}) (module.IChannel, error) {
+
+	mt.Lock()
+	defer mt.Unlock()
+
+	c, ok := mt.channelMap[channel]
+	if !ok {
+		c = newChannel(mt, channel)
+		mt.channelMap[channel] = c
+		mt.log.Infof("[braid.pubsub ]Topic %v new channel %v", mt.topic, channel)
+	}
+
+	return c.addConsumer(), nil
+}
+
+// rmvConsumer 移除消费者，当 channel 中没有消费者时同时移除 channel
+func (mt *memoryTopic) rmvConsumer(consumer *memoryConsumer) {
+	mt.Lock()
+	defer mt.Unlock()
+
+	if !consumer.c.rmvConsumer(consumer) && mt.channelMap[consumer.c.name] == consumer.c {
+		delete(mt.channelMap, consumer.c.name)
+	}
+}
+
+// Close 当 topic 中已经没有 channel 时，将 topic 从进程中移除
+func (mt *memoryTopic) Close() error {
+
+	mt.b.Lock()
+	defer mt.b.Unlock()
+
+	mt.RLock()
+	empty := len(mt.channelMap) == 0
+	mt.RUnlock()
+
+	if empty && mt.b.topicMap[mt.topic] == mt {
+		delete(mt.b.topicMap, mt.topic)
+	}
+
+	return nil
+}
@@ -79,6 +79,18 @@ func (nmb *nsqPubsub) Info() {
 
 }
 
+// Close 停止本地所有 topic 的生产者以及消费者（不会删除 nsqd 中的 topic
+func (nmb *nsqPubsub) Close() {
+	nmb.Lock()
+	topics := nmb.topicMap
+	nmb.topicMap = make(map[string]*pubsubTopic)
+	nmb.Unlock()
+
+	for _, t := range topics {
+		t.exit()
+	}
+}
+
 func (nmb *nsqPubsub) rmvTopic(name string) error {
 	nmb.RLock()
 	topic, ok := nmb.topicMap[name]
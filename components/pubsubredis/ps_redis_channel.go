@@ -2,6 +2,7 @@ package pubsubredis
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"time"
 
@@ -51,13 +52,20 @@ func newChannel(ctx context.Context, topic, channel string, client *redis.Client
 func (c *psRedisChannel) loop() {
 	go func() {
 		for {
-			msgs := c.client.XReadGroup(context.TODO(), &redis.XReadGroupArgs{
+			if atomic.LoadInt32(&c.exitFlag) == 1 {
+				return
+			}
+
+			msgs, err := c.client.XReadGroup(context.TODO(), &redis.XReadGroupArgs{
 				Group:    c.channel,
 				Consumer: c.consumer,
 				Streams:  []string{c.topic, ">"},
 				Block:    100 * time.Millisecond,
 				Count:    10,
-			}).Val()
+			}).Result()
+			if errors.Is(err, redis.ErrClosed) {
+				return
+			}
 
 			for _, v := range msgs {
 				for _, msg := range v.Messages {
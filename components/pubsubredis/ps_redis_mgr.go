@@ -1,6 +1,7 @@
 package pubsubredis
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pojol/braid-go/components/depends/blog"
@@ -43,6 +44,16 @@ func (nps *redisPubsub) Info() {
 
 }
 
+// Health 检查 redis 是否可以访问
+func (nps *redisPubsub) Health(ctx context.Context) module.HealthStatus {
+	err := nps.client.Ping(ctx).Err()
+	if err != nil {
+		return module.HealthStatus{Live: true, Message: "redis ping err " + err.Error()}
+	}
+
+	return module.HealthStatus{Live: true, Ready: true}
+}
+
 func (nps *redisPubsub) GetTopic(name string) module.ITopic {
 	var t *redisTopic
 
@@ -54,8 +65,23 @@ func (nps *redisPubsub) GetTopic(name string) module.ITopic {
 	}
 
 	nps.Lock()
-	t = newTopic(name, nps.client, nps, nps.log)
-	nps.Unlock()
+	defer nps.Unlock()
+
+	t, ok = nps.topicMap[name]
+	if !ok {
+		t = newTopic(name, nps.client, nps, nps.log)
+		nps.topicMap[name] = t
+	}
 
 	return t
 }
+
+// Close 停止本地所有 channel 的消息读取（不会删除 redis 中的 stream 以及消费组
+func (nps *redisPubsub) Close() {
+	nps.RLock()
+	defer nps.RUnlock()
+
+	for _, t := range nps.topicMap {
+		t.exit()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pojol/braid-go/components/depends/blog"
 	"github.com/pojol/braid-go/module"
@@ -110,6 +111,15 @@ func (rt *redisTopic) Close() error {
 	return err
 }
 
+func (rt *redisTopic) exit() {
+	rt.RLock()
+	defer rt.RUnlock()
+
+	for _, c := range rt.channelMap {
+		atomic.StoreInt32(&c.exitFlag, 1)
+	}
+}
+
 func (rt *redisTopic) getOrCreateChannel(ctx context.Context, name string, p ChannelParm) (module.IChannel, error) {
 
 	//channel, ok := rt.channelMap[name]
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
@@ -12,21 +13,14 @@ import (
 
 	"github.com/pojol/braid-go/components/depends/blog"
 	"github.com/pojol/braid-go/components/internal/balancer"
+	"github.com/pojol/braid-go/components/internal/pool"
+	"github.com/pojol/braid-go/components/internal/tlsreload"
 	"github.com/pojol/braid-go/module"
 	"github.com/pojol/braid-go/module/meta"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
-)
-
-var (
-	// ErrServiceNotAvailable 服务不可用，通常是因为没有查询到中心节点(coordinate)
-	ErrServiceNotAvailable = errors.New("caller service not available")
-
-	// ErrConfigConvert 配置转换失败
-	ErrConfigConvert = errors.New("convert linker config")
-
-	// ErrCantFindNode 在注册中心找不到对应的服务节点
-	ErrCantFindNode = errors.New("can't find service node in center")
+	"google.golang.org/grpc/credentials"
 )
 
 // Client 调用器
@@ -43,6 +37,11 @@ type grpcClient struct {
 	ps           module.IPubsub
 
 	connmap sync.Map
+
+	budget   *retryBudget
+	breakers *breakerGroup
+
+	tls *tlsreload.Reloader
 }
 
 func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, b balancer.IBalancer, linkcache module.ILinkCache, ps module.IPubsub, opts ...Option) module.IClient {
@@ -53,68 +52,98 @@ func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, b balancer.IBalanc
 		opt(&p)
 	}
 
-	return &grpcClient{
+	c := &grpcClient{
 		info:      info,
 		log:       log,
 		b:         b,
 		linkcache: linkcache,
 		ps:        ps,
 		parm:      p,
+		budget:    newRetryBudget(p.RetryBudgetRatio, p.RetryBudgetMinPerSecond),
+	}
+
+	if p.BreakerPolicy.enabled() {
+		c.breakers = newBreakerGroup(p.BreakerPolicy, p.BreakerListener, func(address, from, to string) {
+			c.log.Warnf("[braid.client] circuit breaker addr : %v %v -> %v", address, from, to)
+		})
+	}
+
+	if p.TLS {
+		c.tls = tlsreload.New(p.TLSCertFile, p.TLSKeyFile, p.TLSCAFile)
+	}
+
+	return c
+}
+
+// serverName 验证服务端证书时使用的名称
+func (c *grpcClient) serverName(addr, name string) string {
+	if c.parm.TLSVerifyServiceName {
+		return name
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
 	}
+	return host
 }
 
-func (c *grpcClient) newconn(addr string) (*grpc.ClientConn, error) {
+func (c *grpcClient) newconn(addr, name string) (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var conn *grpc.ClientConn
-	var err error
+	dialopts := []grpc.DialOption{}
 
-	if len(c.parm.UnaryInterceptors) > 0 {
-		conn, err = grpc.DialContext(ctx, addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(c.parm.UnaryInterceptors...)))
-		if err != nil {
-			goto EXT
-		}
+	if c.tls != nil {
+		dialopts = append(dialopts, grpc.WithTransportCredentials(
+			credentials.NewTLS(c.tls.ClientConfig(c.serverName(addr, name))),
+		))
 	} else {
-		conn, err = grpc.DialContext(ctx, addr, grpc.WithInsecure())
-		if err != nil {
-			goto EXT
-		}
+		dialopts = append(dialopts, grpc.WithInsecure())
+	}
+
+	if len(c.parm.UnaryInterceptors) > 0 {
+		dialopts = append(dialopts, grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(c.parm.UnaryInterceptors...)))
+	}
+
+	if len(c.parm.StreamInterceptors) > 0 {
+		dialopts = append(dialopts, grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(c.parm.StreamInterceptors...)))
 	}
 
-EXT:
+	conn, err := grpc.DialContext(ctx, addr, dialopts...)
 	c.log.Infof("[braid.client] new connect addr : %v err : %v", addr, err)
 
 	return conn, err
 }
 
-func (c *grpcClient) closeconn(conn *grpc.ClientConn) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	doneCh := make(chan error)
-	go func() {
-		var result error
-		if err := conn.Close(); err != nil {
-			result = fmt.Errorf("[braid.client] %w %v", err, "failed to close gRPC client")
-		}
-		doneCh <- result
-	}()
-
-	select {
-	case <-ctx.Done():
-		return errors.New("failed to close gRPC client because of timeout")
-	case err := <-doneCh:
-		c.log.Infof("[braid.client] close connect addr : %v err : %v", conn.Target(), err)
-		return err
-	}
+// newpool 为节点创建连接池
+func (c *grpcClient) newpool(addr, name string) (*pool.GRPCPool, error) {
+	return pool.NewGRPCPool(func() (*grpc.ClientConn, error) {
+		return c.newconn(addr, name)
+	}, c.parm.PoolInitNum, c.parm.PoolCapacity, c.parm.PoolIdle)
 }
 
 func (c *grpcClient) Init() error {
 	var err error
 
-	c.b.Init() // 初始化自身的负载均衡器
-	defer c.b.Run()
+	if c.parm.Strategy != "" && !validStrategy(c.parm.Strategy) {
+		return fmt.Errorf("[braid.client] unsupported strategy %v", c.parm.Strategy)
+	}
+
+	if c.tls != nil {
+		err = c.tls.Load()
+		if err != nil {
+			return fmt.Errorf("[braid.client] load tls err %w", err)
+		}
+
+		c.tls.Watch(c.parm.TLSReloadInterval, func(err error) {
+			if err != nil {
+				c.log.Warnf("[braid.client] reload tls err %s", err.Error())
+			} else {
+				c.log.Infof("[braid.client] reload tls %v", c.parm.TLSCertFile)
+			}
+		})
+	}
 
 	c.discoverchan, err = c.ps.GetTopic(meta.TopicDiscoverServiceUpdate).
 		Sub(context.TODO(), meta.ModuleClient+"-"+c.info.ID)
@@ -127,23 +156,22 @@ func (c *grpcClient) Init() error {
 		if dmsg.Event == meta.TopicDiscoverServiceNodeAdd {
 			_, ok := c.connmap.Load(dmsg.Nod.Address)
 			if !ok {
-				conn, err := c.newconn(dmsg.Nod.Address)
+				p, err := c.newpool(dmsg.Nod.Address, dmsg.Nod.Name)
 				if err != nil {
-					c.log.Errf("[braid.client] new grpc conn err %s", err.Error())
-				} else {
-					c.connmap.Store(dmsg.Nod.Address, conn)
+					c.log.Warnf("[braid.client] new grpc pool err %s, addr : %v", err.Error(), dmsg.Nod.Address)
+					return nil
 				}
+				c.connmap.Store(dmsg.Nod.Address, p)
 			}
 		} else if dmsg.Event == meta.TopicDiscoverServiceNodeRmv {
-			mc, ok := c.connmap.Load(dmsg.Nod.Address)
+			mc, ok := c.connmap.LoadAndDelete(dmsg.Nod.Address)
 			if ok {
-				conn := mc.(*grpc.ClientConn)
-				err = c.closeconn(conn)
-				if err != nil {
-					c.log.Errf("[braid.client] close grpc conn err %s", err.Error())
-				} else {
-					c.connmap.Delete(dmsg.Nod.Address)
-				}
+				mc.(*pool.GRPCPool).Close()
+				c.log.Infof("[braid.client] close conns addr : %v", dmsg.Nod.Address)
+			}
+
+			if c.breakers != nil {
+				c.breakers.remove(dmsg.Nod.Address)
 			}
 		}
 		return nil
@@ -152,15 +180,16 @@ func (c *grpcClient) Init() error {
 	return nil
 }
 
-func (c *grpcClient) getConn(address string) (*grpc.ClientConn, error) {
+// getConn 轮询获取节点连接池中的一个共享连接（不会阻塞，使用结束后通过 putConn 放回
+func (c *grpcClient) getConn(address string) (*pool.ClientConn, error) {
 	mc, ok := c.connmap.Load(address)
 	if !ok {
-		return nil, fmt.Errorf("gRPC client Can't find target %s", address)
+		return nil, &CallError{Err: ErrConnNotFound, Address: address}
 	}
 
-	conn, ok := mc.(*grpc.ClientConn)
-	if !ok {
-		return nil, fmt.Errorf("gRPC client failed address : %s", address)
+	conn, err := mc.(*pool.GRPCPool).Shared()
+	if err != nil {
+		return nil, &CallError{Err: ErrConnNotFound, Address: address, Cause: err}
 	}
 
 	if conn.GetState() == connectivity.TransientFailure {
@@ -171,42 +200,78 @@ func (c *grpcClient) getConn(address string) (*grpc.ClientConn, error) {
 	return conn, nil
 }
 
-func (c *grpcClient) pick(nodName string, token string, link bool) (meta.Node, error) {
+// putConn 放回连接，连接的传输层不可用时将连接标记为 unhealthy（下一次使用时重新创建
+//
+//	服务端返回的 codes.Unavailable 不会影响连接的状态
+func (c *grpcClient) putConn(conn *pool.ClientConn, err error) {
+	if err != nil && Code(err) == codes.Unavailable && conn.GetState() != connectivity.Ready {
+		c.log.Warnf("[braid.client] unhealthy conn %v", conn.Target())
+		conn.Unhealthy()
+	}
 
-	var nod meta.Node
-	var err error
+	conn.Put()
+}
 
-	if token == "" && link {
-		nod, err = c.b.Pick(balancer.StrategyRandom, nodName)
-	} else {
-		nod, err = c.b.Pick(balancer.StrategySwrr, nodName)
+// strategy 调用使用的策略，没有指定时使用默认的策略
+func (c *grpcClient) strategy(strategy string, token string) string {
+
+	if strategy == "" {
+		strategy = c.parm.Strategy
 	}
 
-	if err != nil {
-		return nod, err
+	if strategy == "" {
+		if token == "" && c.linkcache != nil {
+			strategy = balancer.StrategyRandom
+		} else {
+			strategy = balancer.StrategySwrr
+		}
 	}
 
-	if nod.Address == "" {
-		return nod, errors.New("address is not available")
+	return strategy
+}
+
+func (c *grpcClient) pick(nodName string, token string, strategy string, filters ...balancer.Filter) (meta.Node, error) {
+
+	var nod meta.Node
+	var err error
+
+	strategy = c.strategy(strategy, token)
+
+	nod, err = c.b.Pick(strategy, nodName, filters...)
+
+	if err != nil || nod.Address == "" {
+		// 服务没有节点，或者所有的节点都被过滤掉了
+		if len(c.b.Nodes(nodName)) == 0 {
+			return nod, &CallError{Err: ErrCantFindNode, Target: nodName, Token: token, Cause: err}
+		}
+		return nod, &CallError{Err: ErrServiceNotAvailable, Target: nodName, Token: token, Cause: err}
 	}
 
 	return nod, nil
 }
 
-func (c *grpcClient) findTarget(ctx context.Context, token string, target string) string {
+func (c *grpcClient) findTarget(ctx context.Context, token string, target string, strategy string, filters ...balancer.Filter) (string, error) {
 	var address string
 	var err error
 	var nod meta.Node
 
 	if (c.linkcache != nil) && token != "" {
 		address, _ = c.linkcache.Target(token, target)
+
+		// 链接的节点已经退出
+		if address != "" {
+			if _, ok := c.connmap.Load(address); !ok {
+				c.linkcache.Unlink(token)
+				return "", &CallError{Err: ErrLinkBroken, Target: target, Token: token, Address: address}
+			}
+		}
 	}
 
 	if address == "" {
-		nod, err = c.pick(target, token, c.linkcache != nil)
+		nod, err = c.pick(target, token, strategy, filters...)
 		if err != nil {
 			c.log.Warnf("[braid.client] pick warning %s", err.Error())
-			return ""
+			return "", err
 		}
 
 		address = nod.Address
@@ -218,45 +283,297 @@ func (c *grpcClient) findTarget(ctx context.Context, token string, target string
 		}
 	}
 
-	return address
+	return address, nil
 }
 
-// Invoke grpc call
-func (c *grpcClient) Invoke(ctx context.Context, nodName, methon, token string, args, reply interface{}, opts ...interface{}) error {
+// findNode 在 target 服务的节点中查找指定的节点
+func (c *grpcClient) findNode(target string, cp *CallParm) (meta.Node, error) {
+
+	nods := c.b.Nodes(target, func(nod meta.Node) bool {
+		if cp.NodeID != "" && nod.ID != cp.NodeID {
+			return false
+		}
+		if cp.Node != "" && nod.Address != cp.Node {
+			return false
+		}
+		return true
+	})
+
+	if len(nods) == 0 {
+		node := cp.NodeID
+		if node == "" {
+			node = cp.Node
+		}
+		return meta.Node{}, &NodeNotFoundError{Target: target, Node: node}
+	}
+
+	return nods[0], nil
+}
+
+// link 将 token 的链路指向指定的节点，token 已经链接到了其他的节点时先解除 token 的所有链路
+func (c *grpcClient) link(token string, nod meta.Node) {
+
+	linked, _ := c.linkcache.Target(token, nod.Name)
+	if linked == nod.Address {
+		return
+	}
+
+	if linked != "" {
+		c.linkcache.Unlink(token)
+	}
+
+	err := c.linkcache.Link(token, nod)
+	if err != nil {
+		c.log.Warnf("[braid.client] link warning %s %s %s", token, nod.Name, err.Error())
+	}
+}
+
+func validStrategy(strategy string) bool {
+	return strategy == StrategyRandom || strategy == StrategySwrr || strategy == StrategyP2C
+}
+
+// parseCallOptions 解析 Invoke 传入的调用选项，支持 CallOption 和 grpc.CallOption
+func parseCallOptions(opts []interface{}) (CallParm, error) {
+
+	cp := CallParm{}
+
+	for _, v := range opts {
+		switch opt := v.(type) {
+		case nil:
+		case CallOption:
+			opt(&cp)
+		case grpc.CallOption:
+			cp.GrpcOpts = append(cp.GrpcOpts, opt)
+		default:
+			return cp, &CallError{Err: ErrInvalidCallOption, Cause: fmt.Errorf("unsupported call option type %T", v)}
+		}
+	}
+
+	if cp.Strategy != "" && !validStrategy(cp.Strategy) {
+		return cp, &CallError{Err: ErrInvalidCallOption, Cause: fmt.Errorf("unsupported call strategy %v", cp.Strategy)}
+	}
+
+	return cp, nil
+}
+
+// target 选取节点并获取节点的连接，返回错误时请求还没有发送出去
+//
+//	tried 已经尝试过的节点地址，重试时优先选取其他的节点
+func (c *grpcClient) target(ctx context.Context, nodName, token string, cp *CallParm, tried map[string]bool) (string, *pool.ClientConn, error) {
 
 	var address string
-	var grpcopts []grpc.CallOption
+	var err error
+	var filters []balancer.Filter
+
+	// 熔断的节点不会被选取
+	if c.breakers != nil {
+		filters = append(filters, func(nod meta.Node) bool {
+			return c.breakers.available(nod.Address)
+		})
+	}
+
+	if cp.pinned() {
+		nod, err := c.findNode(nodName, cp)
+		if err != nil {
+			return "", nil, err
+		}
+		address = nod.Address
+
+		if cp.Link && c.linkcache != nil && token != "" && len(tried) == 0 {
+			c.link(token, nod)
+		}
+	} else if len(tried) != 0 {
+		address, _ = c.findTarget(ctx, token, nodName, cp.Strategy, append(filters, func(nod meta.Node) bool {
+			return !tried[nod.Address]
+		})...)
+	}
 
-	address = c.findTarget(ctx, token, nodName)
+	// 没有其他可以选取的节点时，允许选取已经尝试过的节点
 	if address == "" {
-		return fmt.Errorf("find target warning token : %s node : %s", token, nodName)
+		address, err = c.findTarget(ctx, token, nodName, cp.Strategy, filters...)
+		if err != nil {
+			return address, nil, err
+		}
+	}
+
+	tried[address] = true
+
+	// linkcache 中的节点和指定的节点不经过负载均衡，需要再次检查熔断状态
+	if c.breakers != nil && !c.breakers.acquire(address) {
+		if c.linkcache != nil && (!cp.pinned() || cp.Link) && token != "" {
+			c.linkcache.Unlink(token)
+		}
+		return address, nil, &CallError{Err: ErrBreakerOpen, Target: nodName, Token: token, Address: address}
 	}
 
 	conn, err := c.getConn(address)
 	if err != nil {
+		var ce *CallError
+		if errors.As(err, &ce) {
+			ce.Target, ce.Token = nodName, token
+		}
+
 		c.log.Warnf("[braid.client] client get conn warning %s", err.Error())
-		return err
+		if c.breakers != nil {
+			c.breakers.release(address)
+		}
+		return address, nil, err
 	}
 
-	if len(opts) != 0 {
-		for _, v := range opts {
-			callopt, ok := v.(grpc.CallOption)
-			if !ok {
-				c.log.Warnf("[braid.client] call option type mismatch")
-			}
-			grpcopts = append(grpcopts, callopt)
+	return address, conn, nil
+}
+
+// done 记录请求的结果，失败时移除 token 的链路信息
+//
+//	调用方的 ctx 超时或取消引起的错误不计入熔断的统计（只归还 acquire 占用的探测请求
+func (c *grpcClient) done(ctx context.Context, nodName, methon, token, address string, cp *CallParm, err error) {
+
+	if c.breakers != nil {
+		if callerCanceled(ctx, err) {
+			c.breakers.release(address)
+		} else {
+			c.breakers.done(address, err)
 		}
 	}
 
-	err = conn.Invoke(ctx, methon, args, reply, grpcopts...)
 	if err != nil {
 		c.log.Warnf("[braid.client] invoke warning %s, target = %s, methon = %s, addr = %s, token = %s", err.Error(), nodName, methon, address, token)
-		if c.linkcache != nil {
+		if c.linkcache != nil && (!cp.pinned() || cp.Link) && token != "" {
 			c.linkcache.Unlink(token)
 		}
 	}
+}
+
+// invoke 发起一次调用，返回调用的节点地址，以及请求是否已经发送到了节点
+//
+//	请求被中间件拦截时视为已经发送（由中间件返回的错误码决定是否重试
+func (c *grpcClient) invoke(ctx context.Context, nodName, methon, token string, args, reply interface{}, cp *CallParm, tried map[string]bool, attempt int) (string, bool, error) {
+
+	address, conn, err := c.target(ctx, nodName, token, cp, tried)
+	if err != nil {
+		return address, false, err
+	}
+
+	info := &CallInfo{
+		Target:   nodName,
+		Methon:   methon,
+		Token:    token,
+		Strategy: c.strategy(cp.Strategy, token),
+		Node:     meta.Node{Name: nodName, Address: address},
+		Attempt:  attempt,
+	}
+
+	sent, err := c.send(ctx, info, conn.ClientConn, args, reply, cp)
+	c.putConn(conn, err)
+	if !sent {
+		if c.breakers != nil {
+			c.breakers.release(address)
+		}
+		return address, true, err
+	}
+
+	c.done(ctx, nodName, methon, token, address, cp, err)
 
-	return err
+	return address, true, err
+}
+
+// Invoke grpc call
+//
+//	opts 支持 CallOption（超时，token，策略，指定节点）和 grpc.CallOption
+//	调用失败时按照重试策略（见 WithRetryPolicy）重新选取节点进行重试，超时时间包含所有的重试
+func (c *grpcClient) Invoke(ctx context.Context, nodName, methon, token string, args, reply interface{}, opts ...interface{}) error {
+
+	cp, err := parseCallOptions(opts)
+	if err != nil {
+		c.log.Warnf("[braid.client] %s, target = %s, methon = %s", err.Error(), nodName, methon)
+		return err
+	}
+
+	if cp.Token != "" {
+		token = cp.Token
+	}
+
+	if cp.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cp.Timeout)
+		defer cancel()
+	}
+
+	policy := c.retryPolicy(nodName, methon)
+	tried := make(map[string]bool)
+
+	c.budget.deposit()
+
+	for attempt := 1; ; attempt++ {
+		address, sent, err := c.invoke(ctx, nodName, methon, token, args, reply, &cp, tried, attempt)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= policy.MaxAttempts || !policy.retryable(err, sent) || ctx.Err() != nil {
+			return err
+		}
+
+		if !c.budget.withdraw() {
+			c.log.Warnf("[braid.client] retry budget exhausted, target = %s, methon = %s", nodName, methon)
+			return err
+		}
+
+		backoff := policy.backoff(attempt)
+		c.log.Infof("[braid.client] retry attempt %d after %v, target = %s, methon = %s, addr = %s", attempt+1, backoff, nodName, methon, address)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// NewStream 创建一个流式调用，节点的选取方式和 Invoke 相同（流式调用不会重试
+//
+//	opts 支持 CallOption 和 grpc.CallOption，WithCallTimeout 的超时时间包含整个流的生命周期
+func (c *grpcClient) NewStream(ctx context.Context, nodName, methon, token string, desc *grpc.StreamDesc, opts ...interface{}) (grpc.ClientStream, error) {
+
+	cp, err := parseCallOptions(opts)
+	if err != nil {
+		c.log.Warnf("[braid.client] %s, target = %s, methon = %s", err.Error(), nodName, methon)
+		return nil, err
+	}
+
+	if cp.Token != "" {
+		token = cp.Token
+	}
+
+	address, conn, err := c.target(ctx, nodName, token, &cp, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	cancel := func() {}
+	if cp.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cp.Timeout)
+	}
+
+	stream, err := conn.NewStream(ctx, desc, methon, cp.GrpcOpts...)
+	if err != nil {
+		err = &RemoteError{Target: nodName, Methon: methon, Address: address, Err: err}
+	}
+	c.done(ctx, nodName, methon, token, address, &cp, err)
+	if err != nil {
+		c.putConn(conn, err)
+		cancel()
+		return nil, err
+	}
+
+	// 流结束后放回连接，释放超时的 context
+	go func() {
+		<-stream.Context().Done()
+		conn.Put()
+		cancel()
+	}()
+
+	return stream, nil
 }
 
 func (s *grpcClient) Name() string {
@@ -268,6 +585,17 @@ func (c *grpcClient) Run() {
 }
 
 func (c *grpcClient) Close() {
-	c.discoverchan.Close()
-	c.b.Close()
+	if c.discoverchan != nil {
+		c.discoverchan.Close()
+	}
+
+	c.connmap.Range(func(key, value interface{}) bool {
+		c.connmap.Delete(key)
+		value.(*pool.GRPCPool).Close()
+		return true
+	})
+
+	if c.tls != nil {
+		c.tls.Close()
+	}
 }
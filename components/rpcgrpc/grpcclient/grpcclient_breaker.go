@@ -0,0 +1,308 @@
+package grpcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 熔断器的状态
+const (
+	// BreakerClosed 正常状态，请求可以发送到节点
+	BreakerClosed = "closed"
+
+	// BreakerOpen 熔断状态，节点不会被负载均衡选取
+	BreakerOpen = "open"
+
+	// BreakerHalfOpen 半开状态，允许少量的探测请求，探测成功后恢复为 BreakerClosed
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerPolicy 节点的熔断策略，ConsecutiveFailures 和 ErrorRate 都为 0 时不启用熔断
+type BreakerPolicy struct {
+	// 连续失败的次数达到阈值时熔断
+	ConsecutiveFailures int
+
+	// 统计窗口内的错误率达到阈值（0 ~ 1）时熔断
+	ErrorRate float64
+	// 统计错误率需要的最少请求数
+	MinRequests int
+	// 错误率的统计窗口
+	Window time.Duration
+
+	// 熔断的持续时间，之后进入半开状态
+	OpenTimeout time.Duration
+
+	// 半开状态下同时允许的探测请求数
+	HalfOpenRequests int
+
+	// 视为节点故障的 grpc 错误码，为空时使用 Unavailable, DeadlineExceeded, ResourceExhausted, Internal
+	// 调用方的 ctx 超时或取消引起的 DeadlineExceeded, Canceled 不会视为节点故障
+	FailureCodes []codes.Code
+}
+
+var (
+	// DefaultBreakerPolicy 默认的熔断策略
+	DefaultBreakerPolicy = BreakerPolicy{
+		ConsecutiveFailures: 5,
+		ErrorRate:           0.5,
+		MinRequests:         20,
+		Window:              time.Second * 10,
+		OpenTimeout:         time.Second * 5,
+		HalfOpenRequests:    1,
+	}
+
+	defaultFailureCodes = []codes.Code{
+		codes.Unavailable,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Internal,
+	}
+)
+
+// BreakerListener 熔断器状态变更的回调
+type BreakerListener func(address string, from, to string)
+
+func (bp *BreakerPolicy) enabled() bool {
+	return bp.ConsecutiveFailures > 0 || bp.ErrorRate > 0
+}
+
+// nodeFailure 错误是否是节点的故障（见 defaultFailureCodes
+func nodeFailure(err error) bool {
+	return (&BreakerPolicy{}).failure(err)
+}
+
+// callerCanceled 错误是否由调用方的 ctx 超时或取消引起（而不是节点返回的超时
+func callerCanceled(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() == nil {
+		return false
+	}
+
+	code := Code(err)
+	return code == codes.DeadlineExceeded || code == codes.Canceled
+}
+
+func (bp *BreakerPolicy) failure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	lst := bp.FailureCodes
+	if len(lst) == 0 {
+		lst = defaultFailureCodes
+	}
+
+	code := status.Code(err)
+	for _, c := range lst {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+type breaker struct {
+	state string
+
+	consecutive int
+
+	windowAt time.Time
+	total    int
+	failed   int
+
+	openAt time.Time
+	probes int
+}
+
+// breakerGroup 按照节点地址管理熔断器
+type breakerGroup struct {
+	policy   BreakerPolicy
+	listener BreakerListener
+	onChange func(address string, from, to string)
+
+	breakers map[string]*breaker
+
+	sync.Mutex
+}
+
+func newBreakerGroup(policy BreakerPolicy, listener BreakerListener, onChange func(address string, from, to string)) *breakerGroup {
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = 1
+	}
+
+	return &breakerGroup{
+		policy:   policy,
+		listener: listener,
+		onChange: onChange,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+func (bg *breakerGroup) get(address string) *breaker {
+	b, ok := bg.breakers[address]
+	if !ok {
+		b = &breaker{state: BreakerClosed, windowAt: time.Now()}
+		bg.breakers[address] = b
+	}
+	return b
+}
+
+// transition 变更状态，需要在持有锁的情况下调用；回调在释放锁之后执行
+func (bg *breakerGroup) transition(address string, b *breaker, to string) func() {
+	from := b.state
+	if from == to {
+		return nil
+	}
+
+	b.state = to
+	b.probes = 0
+	b.consecutive = 0
+	b.total, b.failed = 0, 0
+	b.windowAt = time.Now()
+	if to == BreakerOpen {
+		b.openAt = time.Now()
+	}
+
+	return func() {
+		if bg.onChange != nil {
+			bg.onChange(address, from, to)
+		}
+		if bg.listener != nil {
+			bg.listener(address, from, to)
+		}
+	}
+}
+
+// expire 熔断时间结束的节点进入半开状态
+func (bg *breakerGroup) expire(address string, b *breaker) func() {
+	if b.state == BreakerOpen && time.Since(b.openAt) >= bg.policy.OpenTimeout {
+		return bg.transition(address, b, BreakerHalfOpen)
+	}
+	return nil
+}
+
+// available 节点是否可以被选取（用于负载均衡的过滤器
+func (bg *breakerGroup) available(address string) bool {
+	bg.Lock()
+	b, ok := bg.breakers[address]
+	if !ok {
+		bg.Unlock()
+		return true
+	}
+
+	notify := bg.expire(address, b)
+	available := b.state == BreakerClosed ||
+		(b.state == BreakerHalfOpen && b.probes < bg.policy.HalfOpenRequests)
+	bg.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+
+	return available
+}
+
+// acquire 在发送请求之前调用，半开状态下会占用一个探测请求
+func (bg *breakerGroup) acquire(address string) bool {
+	bg.Lock()
+	b := bg.get(address)
+	notify := bg.expire(address, b)
+
+	ok := true
+	switch b.state {
+	case BreakerOpen:
+		ok = false
+	case BreakerHalfOpen:
+		if b.probes >= bg.policy.HalfOpenRequests {
+			ok = false
+		} else {
+			b.probes++
+		}
+	}
+	bg.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+
+	return ok
+}
+
+// release 请求没有发送出去时，归还 acquire 占用的探测请求
+func (bg *breakerGroup) release(address string) {
+	bg.Lock()
+	defer bg.Unlock()
+
+	b, ok := bg.breakers[address]
+	if ok && b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// done 记录请求的结果
+func (bg *breakerGroup) done(address string, err error) {
+	failure := bg.policy.failure(err)
+
+	bg.Lock()
+	b := bg.get(address)
+
+	var notify func()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failure {
+			notify = bg.transition(address, b, BreakerOpen)
+		} else {
+			notify = bg.transition(address, b, BreakerClosed)
+		}
+	case BreakerClosed:
+		if bg.policy.Window > 0 && time.Since(b.windowAt) >= bg.policy.Window {
+			b.windowAt = time.Now()
+			b.total, b.failed = 0, 0
+		}
+
+		b.total++
+		if failure {
+			b.failed++
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+
+		if (bg.policy.ConsecutiveFailures > 0 && b.consecutive >= bg.policy.ConsecutiveFailures) ||
+			(bg.policy.ErrorRate > 0 && b.total >= bg.policy.MinRequests &&
+				float64(b.failed)/float64(b.total) >= bg.policy.ErrorRate) {
+			notify = bg.transition(address, b, BreakerOpen)
+		}
+	}
+	bg.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// state 获取节点的熔断状态
+func (bg *breakerGroup) state(address string) string {
+	bg.Lock()
+	defer bg.Unlock()
+
+	b, ok := bg.breakers[address]
+	if !ok {
+		return BreakerClosed
+	}
+
+	return b.state
+}
+
+// remove 节点退出后移除熔断器
+func (bg *breakerGroup) remove(address string) {
+	bg.Lock()
+	defer bg.Unlock()
+
+	delete(bg.breakers, address)
+}
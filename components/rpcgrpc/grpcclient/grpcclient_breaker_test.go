@@ -0,0 +1,153 @@
+package grpcclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+
+	var changes []string
+	bg := newBreakerGroup(BreakerPolicy{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         time.Millisecond * 50,
+	}, func(address, from, to string) {
+		changes = append(changes, from+"->"+to)
+	}, nil)
+
+	unavailable := status.Error(codes.Unavailable, "")
+
+	// 业务错误不会触发熔断
+	bg.done("a", status.Error(codes.InvalidArgument, ""))
+	bg.done("a", status.Error(codes.InvalidArgument, ""))
+	assert.Equal(t, bg.state("a"), BreakerClosed)
+
+	assert.True(t, bg.acquire("a"))
+	bg.done("a", unavailable)
+	assert.True(t, bg.acquire("a"))
+	bg.done("a", unavailable)
+	assert.Equal(t, bg.state("a"), BreakerOpen)
+	assert.False(t, bg.available("a"))
+	assert.False(t, bg.acquire("a"))
+	assert.True(t, bg.available("b"))
+
+	// 半开状态只允许一个探测请求，探测失败重新熔断
+	time.Sleep(time.Millisecond * 60)
+	assert.True(t, bg.available("a"))
+	assert.True(t, bg.acquire("a"))
+	assert.False(t, bg.available("a"))
+	assert.False(t, bg.acquire("a"))
+	bg.done("a", unavailable)
+	assert.Equal(t, bg.state("a"), BreakerOpen)
+
+	time.Sleep(time.Millisecond * 60)
+	assert.True(t, bg.acquire("a"))
+	bg.release("a")
+	assert.True(t, bg.acquire("a"))
+	bg.done("a", nil)
+	assert.Equal(t, bg.state("a"), BreakerClosed)
+
+	assert.Equal(t, changes, []string{
+		"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed",
+	})
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+
+	bg := newBreakerGroup(BreakerPolicy{
+		ErrorRate:   0.5,
+		MinRequests: 4,
+		Window:      time.Second,
+		OpenTimeout: time.Second,
+	}, nil, nil)
+
+	unavailable := status.Error(codes.Unavailable, "")
+
+	bg.done("a", unavailable)
+	bg.done("a", nil)
+	bg.done("a", unavailable)
+	assert.Equal(t, bg.state("a"), BreakerClosed)
+	bg.done("a", nil)
+	assert.Equal(t, bg.state("a"), BreakerOpen)
+}
+
+func TestBreakerCallerDeadline(t *testing.T) {
+
+	c := &grpcClient{
+		log: blog.BuildWithOption(),
+		breakers: newBreakerGroup(BreakerPolicy{
+			ConsecutiveFailures: 1,
+			OpenTimeout:         time.Second,
+		}, nil, nil),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	deadline := &RemoteError{Err: status.Error(codes.DeadlineExceeded, "")}
+
+	// 调用方的 ctx 超时不会触发熔断
+	assert.True(t, callerCanceled(ctx, deadline))
+	assert.True(t, c.breakers.acquire("a"))
+	c.done(ctx, "svc", "methon", "", "a", &CallParm{}, deadline)
+	assert.Equal(t, c.breakers.state("a"), BreakerClosed)
+
+	// 调用方的 ctx 没有超时，节点返回的超时视为节点故障
+	assert.False(t, callerCanceled(context.Background(), deadline))
+	assert.True(t, c.breakers.acquire("a"))
+	c.done(context.Background(), "svc", "methon", "", "a", &CallParm{}, deadline)
+	assert.Equal(t, c.breakers.state("a"), BreakerOpen)
+}
+
+func TestInvokeBreaker(t *testing.T) {
+
+	fs := &flakyServer{code: codes.Unavailable, fails: 100}
+
+	var mu sync.Mutex
+	var changes []string
+
+	tc := newTestCluster(t,
+		WithCircuitBreaker(BreakerPolicy{
+			ConsecutiveFailures: 2,
+			OpenTimeout:         time.Millisecond * 100,
+		}),
+		WithBreakerListener(func(address, from, to string) {
+			mu.Lock()
+			changes = append(changes, to)
+			mu.Unlock()
+		}),
+	)
+	nod := tc.serve("breaker", fs)
+
+	for i := 0; i < 4; i++ {
+		_, err := tc.call("breaker", "")
+		assert.NotNil(t, err)
+	}
+
+	// 熔断后请求不再发送到节点
+	assert.Equal(t, atomic.LoadInt32(&fs.calls), int32(2))
+	assert.Equal(t, tc.c.breakers.state(nod.Address), BreakerOpen)
+
+	// 熔断时间结束后进入半开状态，探测成功后恢复
+	atomic.StoreInt32(&fs.fails, 0)
+	assert.Eventually(t, func() bool {
+		return tc.c.breakers.available(nod.Address)
+	}, time.Second, time.Millisecond*10)
+
+	res, err := tc.call("breaker", "probe")
+	assert.Nil(t, err)
+	assert.Equal(t, res.ResBody, []byte("probe"))
+
+	mu.Lock()
+	assert.Equal(t, changes, []string{BreakerOpen, BreakerHalfOpen, BreakerClosed})
+	mu.Unlock()
+}
@@ -0,0 +1,111 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+)
+
+// invokeNode 直接调用指定的节点（不重试
+func (c *grpcClient) invokeNode(ctx context.Context, nod meta.Node, methon string, args, reply interface{}, cp *CallParm) error {
+
+	if c.breakers != nil && !c.breakers.acquire(nod.Address) {
+		return &CallError{Err: ErrBreakerOpen, Target: nod.Name, Methon: methon, Address: nod.Address}
+	}
+
+	conn, err := c.getConn(nod.Address)
+	if err != nil {
+		var ce *CallError
+		if errors.As(err, &ce) {
+			ce.Target, ce.Methon = nod.Name, methon
+		}
+
+		if c.breakers != nil {
+			c.breakers.release(nod.Address)
+		}
+		return err
+	}
+
+	info := &CallInfo{
+		Target:  nod.Name,
+		Methon:  methon,
+		Node:    nod,
+		Attempt: 1,
+	}
+
+	sent, err := c.send(ctx, info, conn.ClientConn, args, reply, cp)
+	c.putConn(conn, err)
+	if !sent {
+		if c.breakers != nil {
+			c.breakers.release(nod.Address)
+		}
+		return err
+	}
+
+	c.done(ctx, nod.Name, methon, "", nod.Address, cp, err)
+
+	return err
+}
+
+// InvokeAll 并发调用 target 服务的所有节点，所有节点共享 WithCallTimeout 的超时时间
+//
+//	opts 支持 WithCallTimeout, WithCallQuorum 和 grpc.CallOption（广播调用不会重试
+//	返回的结果和节点一一对应，即使返回了错误，结果中仍然包含每个节点的返回参数和错误
+func (c *grpcClient) InvokeAll(ctx context.Context, nodName, methon string, args interface{}, newReply func() interface{}, opts ...interface{}) ([]module.InvokeResult, error) {
+
+	cp, err := parseCallOptions(opts)
+	if err != nil {
+		c.log.Warnf("[braid.client] %s, target = %s, methon = %s", err.Error(), nodName, methon)
+		return nil, err
+	}
+
+	nods := c.b.Nodes(nodName)
+	if len(nods) == 0 {
+		return nil, &CallError{Err: ErrCantFindNode, Target: nodName, Methon: methon}
+	}
+
+	if cp.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cp.Timeout)
+		defer cancel()
+	}
+
+	results := make([]module.InvokeResult, len(nods))
+	var wg sync.WaitGroup
+
+	for i, nod := range nods {
+		results[i].Node = nod
+		results[i].Reply = newReply()
+
+		wg.Add(1)
+		go func(res *module.InvokeResult) {
+			defer wg.Done()
+			res.Err = c.invokeNode(ctx, res.Node, methon, args, res.Reply, &cp)
+		}(&results[i])
+	}
+
+	wg.Wait()
+
+	succeeded := 0
+	for _, res := range results {
+		if res.Err == nil {
+			succeeded++
+		}
+	}
+
+	quorum := cp.Quorum
+	if quorum <= 0 {
+		quorum = len(nods)
+	}
+
+	if succeeded < quorum {
+		return results, &CallError{Err: ErrQuorumNotReached, Target: nodName, Methon: methon,
+			Cause: fmt.Errorf("succeeded %d/%d quorum %d", succeeded, len(nods), quorum)}
+	}
+
+	return results, nil
+}
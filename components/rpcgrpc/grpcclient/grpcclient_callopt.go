@@ -0,0 +1,108 @@
+package grpcclient
+
+import (
+	"time"
+
+	"github.com/pojol/braid-go/components/internal/balancer"
+	"google.golang.org/grpc"
+)
+
+const (
+	// StrategyRandom 随机选取节点（没有 token 时的默认策略
+	StrategyRandom = balancer.StrategyRandom
+
+	// StrategySwrr 平滑加权轮询（有 token 时的默认策略
+	StrategySwrr = balancer.StrategySwrr
+
+	// StrategyP2C 随机选取两个节点，选择其中延迟（peak-EWMA）和进行中请求数较低的节点
+	StrategyP2C = balancer.StrategyP2C
+)
+
+// CallParm 单次 rpc 调用的配置项
+type CallParm struct {
+	// 调用的超时时间，为 0 时只受传入的 context 约束
+	Timeout time.Duration
+
+	// 用户的唯一标识，不为空时覆盖 Invoke 传入的 token
+	Token string
+
+	// 选取节点的策略，为空时使用 WithStrategy 设置的默认策略（见 StrategyRandom, StrategySwrr, StrategyP2C
+	Strategy string
+
+	// 直接调用指定地址 or ID 的节点，不经过 linkcache 和负载均衡（节点需要在 target 服务中
+	Node   string
+	NodeID string
+
+	// 调用指定的节点时，将节点记录到 token 的链路信息中（linkcache
+	Link bool
+
+	// 广播调用（InvokeAll）需要成功的最少节点数，为 0 时需要所有节点成功
+	Quorum int
+
+	GrpcOpts []grpc.CallOption
+}
+
+// pinned 是否指定了调用的节点
+func (cp *CallParm) pinned() bool {
+	return cp.Node != "" || cp.NodeID != ""
+}
+
+// CallOption 单次 rpc 调用的配置，作为 Invoke 的 opts 传入
+type CallOption func(*CallParm)
+
+// WithCallTimeout 调用的超时时间
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(c *CallParm) {
+		c.Timeout = timeout
+	}
+}
+
+// WithCallToken 调用使用的 token
+func WithCallToken(token string) CallOption {
+	return func(c *CallParm) {
+		c.Token = token
+	}
+}
+
+// WithCallStrategy 选取节点的策略 StrategyRandom, StrategySwrr, StrategyP2C
+func WithCallStrategy(strategy string) CallOption {
+	return func(c *CallParm) {
+		c.Strategy = strategy
+	}
+}
+
+// WithCallNode 直接调用指定地址（meta.Node.Address）的节点，节点不存在时返回 *NodeNotFoundError
+func WithCallNode(address string) CallOption {
+	return func(c *CallParm) {
+		c.Node = address
+	}
+}
+
+// WithCallNodeID 直接调用指定 ID（meta.Node.ID）的节点，节点不存在时返回 *NodeNotFoundError
+func WithCallNodeID(id string) CallOption {
+	return func(c *CallParm) {
+		c.NodeID = id
+	}
+}
+
+// WithCallLink 调用指定的节点（WithCallNode, WithCallNodeID）时，将节点记录到 token 的链路信息中，
+// 之后这个 token 不指定节点的调用也会路由到这个节点（token 已经链接到了其他节点时会被替换
+func WithCallLink() CallOption {
+	return func(c *CallParm) {
+		c.Link = true
+	}
+}
+
+// WithCallQuorum 广播调用（InvokeAll）需要成功的最少节点数，达不到时返回 ErrQuorumNotReached
+func WithCallQuorum(quorum int) CallOption {
+	return func(c *CallParm) {
+		c.Quorum = quorum
+	}
+}
+
+// WithCallGrpcOptions 追加 grpc 的调用选项
+func WithCallGrpcOptions(opts ...grpc.CallOption) CallOption {
+	return func(c *CallParm) {
+		c.GrpcOpts = append(c.GrpcOpts, opts...)
+	}
+}
@@ -0,0 +1,131 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/internal/balancer"
+	"github.com/pojol/braid-go/components/pubsubmemory"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyServer 前 fails 次调用返回 code 错误的节点
+type flakyServer struct {
+	proto.ListenServer
+
+	fails int32
+	code  codes.Code
+	calls int32
+	delay time.Duration
+}
+
+func (fs *flakyServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.RouteRes, error) {
+	time.Sleep(fs.delay)
+	if atomic.AddInt32(&fs.calls, 1) <= atomic.LoadInt32(&fs.fails) {
+		return nil, status.Error(fs.code, "flaky")
+	}
+	return &proto.RouteRes{ResBody: req.ReqBody}, nil
+}
+
+// testCluster 进程内的调用环境（pubsub, balancer, client），节点为侦听随机端口的 grpc server
+type testCluster struct {
+	t *testing.T
+
+	ps module.IPubsub
+	b  balancer.IBalancer
+	c  *grpcClient
+}
+
+func newTestCluster(t *testing.T, opts ...Option) *testCluster {
+
+	info := meta.ServiceInfo{ID: uuid.New().String(), Name: "gate"}
+	log := blog.BuildWithDefaultOption()
+
+	ps := pubsubmemory.BuildWithOption(info, log)
+	b := balancer.BuildWithOption(info, log, ps)
+	c := BuildWithOption(info, log, b, nil, ps, opts...).(*grpcClient)
+
+	b.Init()
+	b.Run()
+	assert.Nil(t, c.Init())
+
+	t.Cleanup(func() {
+		c.Close()
+		b.Close()
+	})
+
+	return &testCluster{t: t, ps: ps, b: b, c: c}
+}
+
+// serve 启动一个 name 服务的节点并广播节点加入，等待 balancer 和 client 收到节点信息
+func (tc *testCluster) serve(name string, srv proto.ListenServer, opts ...grpc.ServerOption) meta.Node {
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tc.t.Fatal(err)
+	}
+
+	rpc := grpc.NewServer(opts...)
+	proto.RegisterListenServer(rpc, srv)
+	go rpc.Serve(lis)
+	tc.t.Cleanup(rpc.Stop)
+
+	nod := meta.Node{ID: uuid.New().String(), Name: name, Address: lis.Addr().String()}
+	tc.ps.GetTopic(meta.TopicDiscoverServiceUpdate).Pub(context.TODO(),
+		meta.EncodeUpdateMsg(meta.TopicDiscoverServiceNodeAdd, nod))
+
+	assert.Eventually(tc.t, func() bool {
+		_, ok := tc.c.connmap.Load(nod.Address)
+		return ok && len(tc.b.Nodes(name, func(n meta.Node) bool { return n.ID == nod.ID })) == 1
+	}, time.Second, time.Millisecond*5)
+
+	return nod
+}
+
+// call 调用 target 服务的 routing 方法
+func (tc *testCluster) call(target string, body string, opts ...interface{}) (*proto.RouteRes, error) {
+	res := &proto.RouteRes{}
+	err := tc.c.Invoke(context.TODO(), target, "/proto.listen/routing", "", &proto.RouteReq{ReqBody: []byte(body)}, res, opts...)
+	return res, err
+}
+
+func TestInvokeConns(t *testing.T) {
+
+	fs := &flakyServer{delay: time.Millisecond * 200}
+
+	tc := newTestCluster(t, WithPoolCapacity(1))
+	tc.serve("conns", fs)
+
+	// 同一个连接上的调用并发执行，不需要等待其他调用结束
+	begin := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tc.call("conns", "", WithCallTimeout(time.Second*5))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, atomic.LoadInt32(&fs.calls), int32(20))
+	assert.Less(t, time.Since(begin), time.Second*2)
+}
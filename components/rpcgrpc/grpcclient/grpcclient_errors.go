@@ -0,0 +1,175 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 调用路径上的错误类型，通过 errors.Is 判断（返回的错误为 *CallError
+var (
+	// ErrServiceNotAvailable 服务不可用，服务存在节点但是没有可以选取的节点（如所有的节点都处于熔断状态
+	ErrServiceNotAvailable = errors.New("caller service not available")
+
+	// ErrConfigConvert 配置转换失败
+	ErrConfigConvert = errors.New("convert linker config")
+
+	// ErrCantFindNode 在注册中心找不到对应的服务节点
+	ErrCantFindNode = errors.New("can't find service node in center")
+
+	// ErrLinkBroken token 在 linkcache 中链接的节点已经不存在了（链接会被解除
+	ErrLinkBroken = errors.New("linked node not available")
+
+	// ErrConnNotFound 没有节点的连接，或者没有从连接池中获取到连接
+	ErrConnNotFound = errors.New("node connection not found")
+
+	// ErrBreakerOpen 节点处于熔断状态
+	ErrBreakerOpen = errors.New("node circuit breaker is open")
+
+	// ErrQuorumNotReached 广播调用成功的节点数没有达到 quorum
+	ErrQuorumNotReached = errors.New("broadcast quorum not reached")
+
+	// ErrInvalidCallOption 调用选项错误
+	ErrInvalidCallOption = errors.New("invalid call option")
+)
+
+// errCodes 调用路径上的错误对应的 grpc 错误码
+var errCodes = map[error]codes.Code{
+	ErrServiceNotAvailable: codes.Unavailable,
+	ErrCantFindNode:        codes.Unavailable,
+	ErrLinkBroken:          codes.Unavailable,
+	ErrConnNotFound:        codes.Unavailable,
+	ErrBreakerOpen:         codes.Unavailable,
+	ErrQuorumNotReached:    codes.Unavailable,
+	ErrInvalidCallOption:   codes.InvalidArgument,
+}
+
+// CallError 请求发送到节点之前的错误（请求没有发送出去
+type CallError struct {
+	// Err 错误的类型 ErrServiceNotAvailable, ErrCantFindNode, ErrLinkBroken, ErrConnNotFound ...
+	Err error
+
+	Target  string
+	Methon  string
+	Token   string
+	Address string
+
+	// Cause 引起错误的底层错误（可以为空
+	Cause error
+}
+
+func (e *CallError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString(e.Err.Error())
+	for _, kv := range [][2]string{
+		{"target", e.Target},
+		{"methon", e.Methon},
+		{"addr", e.Address},
+		{"token", e.Token},
+	} {
+		if kv[1] != "" {
+			sb.WriteString(", " + kv[0] + " = " + kv[1])
+		}
+	}
+
+	if e.Cause != nil {
+		sb.WriteString(" : " + e.Cause.Error())
+	}
+
+	return sb.String()
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// Is 支持通过 errors.Is 判断底层的错误（如 context.DeadlineExceeded
+func (e *CallError) Is(target error) bool {
+	return e.Cause != nil && errors.Is(e.Cause, target)
+}
+
+// GRPCStatus 错误对应的 grpc 状态，status.Code(err) 可以直接获取错误码
+func (e *CallError) GRPCStatus() *status.Status {
+
+	code, ok := errCodes[e.Err]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	if errors.Is(e.Cause, context.DeadlineExceeded) {
+		code = codes.DeadlineExceeded
+	} else if errors.Is(e.Cause, context.Canceled) {
+		code = codes.Canceled
+	}
+
+	return status.New(code, e.Error())
+}
+
+// NodeNotFoundError 指定的节点（WithCallNode, WithCallNodeID）不在服务发现的节点中（errors.Is ErrCantFindNode
+type NodeNotFoundError struct {
+	Target string
+	Node   string
+}
+
+func (e *NodeNotFoundError) Error() string {
+	return fmt.Sprintf("can't find node %s in service %s", e.Node, e.Target)
+}
+
+func (e *NodeNotFoundError) Unwrap() error {
+	return ErrCantFindNode
+}
+
+// GRPCStatus 指定的节点不存在时返回 codes.NotFound（重新调用同一个节点没有意义
+func (e *NodeNotFoundError) GRPCStatus() *status.Status {
+	return status.New(codes.NotFound, e.Error())
+}
+
+// RemoteError 请求发送到节点之后返回的错误（节点返回的错误 or 传输层的错误
+type RemoteError struct {
+	Target  string
+	Methon  string
+	Address string
+
+	// Err 原始的 grpc 错误
+	Err error
+}
+
+func (e *RemoteError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RemoteError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus 节点返回的 grpc 状态
+func (e *RemoteError) GRPCStatus() *status.Status {
+	return status.Convert(e.Err)
+}
+
+// Code 获取错误对应的 grpc 错误码（支持被 fmt.Errorf("%w") 包装过的错误
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	var se interface {
+		GRPCStatus() *status.Status
+	}
+	if errors.As(err, &se) {
+		return se.GRPCStatus().Code()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codes.DeadlineExceeded
+	} else if errors.Is(err, context.Canceled) {
+		return codes.Canceled
+	}
+
+	return codes.Unknown
+}
@@ -0,0 +1,76 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrors(t *testing.T) {
+
+	tests := []struct {
+		err  error
+		is   error
+		code codes.Code
+	}{
+		{&CallError{Err: ErrCantFindNode, Target: "base"}, ErrCantFindNode, codes.Unavailable},
+		{&CallError{Err: ErrServiceNotAvailable, Target: "base"}, ErrServiceNotAvailable, codes.Unavailable},
+		{&CallError{Err: ErrLinkBroken, Token: "token"}, ErrLinkBroken, codes.Unavailable},
+		{&CallError{Err: ErrConnNotFound, Address: "127.0.0.1:14222"}, ErrConnNotFound, codes.Unavailable},
+		{&CallError{Err: ErrConnNotFound, Cause: context.DeadlineExceeded}, context.DeadlineExceeded, codes.DeadlineExceeded},
+		{&CallError{Err: ErrBreakerOpen}, ErrBreakerOpen, codes.Unavailable},
+		{&CallError{Err: ErrInvalidCallOption}, ErrInvalidCallOption, codes.InvalidArgument},
+		{&NodeNotFoundError{Target: "base", Node: "id"}, ErrCantFindNode, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		assert.True(t, errors.Is(tt.err, tt.is), tt.err.Error())
+		assert.Equal(t, status.Code(tt.err), tt.code, tt.err.Error())
+		assert.Equal(t, Code(fmt.Errorf("wrap : %w", tt.err)), tt.code, tt.err.Error())
+	}
+
+	remote := &RemoteError{Target: "base", Err: status.Error(codes.PermissionDenied, "denied")}
+	assert.Equal(t, status.Code(remote), codes.PermissionDenied)
+	assert.Equal(t, Code(fmt.Errorf("wrap : %w", remote)), codes.PermissionDenied)
+	assert.False(t, errors.Is(remote, ErrCantFindNode))
+
+	var re *RemoteError
+	assert.True(t, errors.As(fmt.Errorf("wrap : %w", remote), &re))
+	assert.Equal(t, re.Target, "base")
+
+	assert.Equal(t, (&CallError{Err: ErrLinkBroken, Target: "base", Token: "token", Cause: errors.New("gone")}).Error(),
+		"linked node not available, target = base, token = token : gone")
+
+	assert.Equal(t, Code(nil), codes.OK)
+	assert.Equal(t, Code(errors.New("unknown")), codes.Unknown)
+}
+
+func TestInvokeErrors(t *testing.T) {
+
+	fs := &flakyServer{fails: 1, code: codes.PermissionDenied}
+
+	tc := newTestCluster(t)
+	nod := tc.serve("errors", fs)
+
+	// 节点返回的错误
+	_, err := tc.call("errors", "")
+	var re *RemoteError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, re.Address, nod.Address)
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+
+	// 服务没有节点
+	_, err = tc.call("errors_unknown", "")
+	assert.True(t, errors.Is(err, ErrCantFindNode))
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+
+	// 错误的调用选项
+	_, err = tc.call("errors", "", WithCallStrategy("unknown"))
+	assert.True(t, errors.Is(err, ErrInvalidCallOption))
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
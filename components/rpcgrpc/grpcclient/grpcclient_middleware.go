@@ -0,0 +1,87 @@
+package grpcclient
+
+import (
+	"context"
+
+	"github.com/pojol/braid-go/module/meta"
+	"google.golang.org/grpc"
+)
+
+// CallInfo 调用的路由信息，在选取节点之后传递给中间件
+type CallInfo struct {
+	// 目标服务名称
+	Target string
+	// 目标服务方法
+	Methon string
+	// 用户的唯一标识（可以为空
+	Token string
+	// 选取节点使用的策略
+	Strategy string
+
+	// 选取的节点
+	Node meta.Node
+
+	// 第几次尝试（从 1 开始，重试时递增
+	Attempt int
+}
+
+// Invoker 发送请求到 CallInfo.Node
+type Invoker func(ctx context.Context, info *CallInfo, args, reply interface{}) error
+
+// Middleware 客户端中间件，在选取节点之后、发送请求之前执行（每次重试都会执行
+//
+//	调用 next 将请求发送到节点，不调用 next 时请求不会发送（返回的错误作为调用的结果
+type Middleware func(ctx context.Context, info *CallInfo, args, reply interface{}, next Invoker) error
+
+// chainMiddlewares 按照添加的顺序嵌套中间件，第一个中间件在最外层
+func chainMiddlewares(mws []Middleware, final Invoker) Invoker {
+	invoker := final
+	for i := len(mws) - 1; i >= 0; i-- {
+		mw, next := mws[i], invoker
+		invoker = func(ctx context.Context, info *CallInfo, args, reply interface{}) error {
+			return mw(ctx, info, args, reply, next)
+		}
+	}
+	return invoker
+}
+
+// node 获取地址对应的节点信息
+func (c *grpcClient) node(target, address string) meta.Node {
+	nods := c.b.Nodes(target, func(nod meta.Node) bool {
+		return nod.Address == address
+	})
+	if len(nods) != 0 {
+		return nods[0]
+	}
+
+	return meta.Node{Name: target, Address: address}
+}
+
+// send 经过中间件将请求发送到节点，sent 为 false 时请求被中间件拦截（连接没有被使用
+func (c *grpcClient) send(ctx context.Context, info *CallInfo, conn *grpc.ClientConn, args, reply interface{}, cp *CallParm) (bool, error) {
+
+	sent := false
+	final := func(ctx context.Context, info *CallInfo, args, reply interface{}) error {
+		sent = true
+
+		finish := c.b.Start(info.Target, info.Node.Address)
+		err := conn.Invoke(ctx, info.Methon, args, reply, cp.GrpcOpts...)
+		if err != nil {
+			err = &RemoteError{Target: info.Target, Methon: info.Methon, Address: info.Node.Address, Err: err}
+		}
+		finish(nodeFailure(err) && !callerCanceled(ctx, err))
+
+		return err
+	}
+
+	if len(c.parm.Middlewares) == 0 {
+		return true, final(ctx, info, args, reply)
+	}
+
+	if info.Node.ID == "" {
+		info.Node = c.node(info.Target, info.Node.Address)
+	}
+
+	err := chainMiddlewares(c.parm.Middlewares, final)(ctx, info, args, reply)
+	return sent, err
+}
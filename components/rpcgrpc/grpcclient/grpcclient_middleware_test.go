@@ -0,0 +1,70 @@
+package grpcclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware(t *testing.T) {
+
+	fs := &flakyServer{code: codes.Unavailable}
+
+	policy := DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond * 10
+
+	var mu sync.Mutex
+	var order []string
+	var infos []CallInfo
+
+	trace := func(ctx context.Context, info *CallInfo, args, reply interface{}, next Invoker) error {
+		mu.Lock()
+		order = append(order, "trace")
+		infos = append(infos, *info)
+		mu.Unlock()
+		return next(ctx, info, args, reply)
+	}
+	guard := func(ctx context.Context, info *CallInfo, args, reply interface{}, next Invoker) error {
+		mu.Lock()
+		order = append(order, "guard")
+		mu.Unlock()
+		if string(args.(*proto.RouteReq).ReqBody) == "reject" {
+			return status.Error(codes.PermissionDenied, "rejected by middleware")
+		}
+		return next(ctx, info, args, reply)
+	}
+
+	tc := newTestCluster(t, WithRetryPolicy(policy), AppendMiddlewares(trace, guard))
+	nod := tc.serve("mw", fs)
+
+	atomic.StoreInt32(&fs.fails, 1)
+	res, err := tc.call("mw", "hello", WithCallToken("mw_token"))
+	assert.Nil(t, err)
+	assert.Equal(t, string(res.ResBody), "hello")
+
+	// 每次重试都会经过中间件
+	assert.Equal(t, order, []string{"trace", "guard", "trace", "guard"})
+	assert.Equal(t, len(infos), 2)
+	for k, info := range infos {
+		assert.Equal(t, info.Target, "mw")
+		assert.Equal(t, info.Methon, "/proto.listen/routing")
+		assert.Equal(t, info.Token, "mw_token")
+		assert.Equal(t, info.Node.Address, nod.Address)
+		assert.Equal(t, info.Node.ID, nod.ID)
+		assert.Equal(t, info.Attempt, k+1)
+	}
+
+	// 中间件拦截的请求不会发送到节点
+	atomic.StoreInt32(&fs.calls, 0)
+	atomic.StoreInt32(&fs.fails, 0)
+	_, err = tc.call("mw", "reject")
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	assert.Equal(t, atomic.LoadInt32(&fs.calls), int32(0))
+}
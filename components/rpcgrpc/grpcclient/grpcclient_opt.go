@@ -8,19 +8,53 @@ import (
 
 // Parm 调用器配置项
 type Parm struct {
-	PoolInitNum  int
-	PoolCapacity int
-	PoolIdle     time.Duration
+	// 每个节点的连接池，调用按照轮询共享池中的连接（grpc 在同一个连接上复用多个调用
+	PoolInitNum  int           // 节点加入时预先创建的连接数量（默认在第一次调用时才创建
+	PoolCapacity int           // 每个节点的连接数量
+	PoolIdle     time.Duration // 连接的最大闲置时间，闲置超时的连接在下一次使用时重新创建
+
+	// 调用没有指定策略时使用的默认策略，为空时没有 token 的调用使用 StrategyRandom，有 token 的调用使用 StrategySwrr
+	Strategy string
+
+	// 客户端中间件，在选取节点之后执行（见 Middleware
+	Middlewares []Middleware
 
 	UnaryInterceptors  []grpc.UnaryClientInterceptor
 	StreamInterceptors []grpc.StreamClientInterceptor
+
+	// 默认的重试策略（默认不重试
+	RetryPolicy RetryPolicy
+	// 服务 or 服务+方法 的重试策略，key 为 target 或 target|methon
+	RetryPolicies map[string]RetryPolicy
+
+	// 重试预算，重试的次数不超过调用次数的 RetryBudgetRatio 倍（每秒至少允许 RetryBudgetMinPerSecond 次
+	RetryBudgetRatio        float64
+	RetryBudgetMinPerSecond int
+
+	// 节点的熔断策略（默认不启用
+	BreakerPolicy   BreakerPolicy
+	BreakerListener BreakerListener
+
+	// 是否使用 tls 连接服务端
+	TLS bool
+	// 验证服务端证书使用的 CA 文件，为空时使用系统的根证书
+	TLSCAFile string
+	// 客户端证书，服务端开启 mTLS 时需要设置
+	TLSCertFile string
+	TLSKeyFile  string
+	// 验证服务端证书中的名称与服务发现中的服务名称一致（默认验证节点地址中的 host
+	TLSVerifyServiceName bool
+	// 检查证书文件变更的间隔，为 0 时不热加载
+	TLSReloadInterval time.Duration
 }
 
 var (
 	DefaultClientParm = Parm{
-		PoolInitNum:  8,
-		PoolCapacity: 64,
+		PoolCapacity: 2,
 		PoolIdle:     time.Second * 100,
+
+		RetryBudgetRatio:        0.2,
+		RetryBudgetMinPerSecond: 10,
 	}
 )
 
@@ -34,7 +68,7 @@ func WithPoolInitNum(num int) Option {
 	}
 }
 
-// WithPoolCapacity 连接池的容量大小
+// WithPoolCapacity 连接池的容量大小（每个节点的连接数量
 func WithPoolCapacity(num int) Option {
 	return func(c *Parm) {
 		c.PoolCapacity = num
@@ -48,6 +82,22 @@ func WithPoolIdle(second int) Option {
 	}
 }
 
+// WithStrategy 调用没有指定策略（WithCallStrategy）时使用的默认策略 StrategyRandom, StrategySwrr, StrategyP2C
+func WithStrategy(strategy string) Option {
+	return func(c *Parm) {
+		c.Strategy = strategy
+	}
+}
+
+// AppendMiddlewares 添加客户端中间件，可以获取调用的目标服务，token，选取的节点以及重试次数
+//
+//	和 grpc 拦截器不同，中间件在 braid 选取节点之后执行，按照添加的顺序嵌套（先添加的在外层
+func AppendMiddlewares(mws ...Middleware) Option {
+	return func(c *Parm) {
+		c.Middlewares = append(c.Middlewares, mws...)
+	}
+}
+
 func AppendUnaryInterceptors(interceptor grpc.UnaryClientInterceptor) Option {
 	return func(c *Parm) {
 		c.UnaryInterceptors = append(c.UnaryInterceptors, interceptor)
@@ -59,3 +109,86 @@ func AppendStreamInterceptors(interceptor grpc.StreamClientInterceptor) Option {
 		c.StreamInterceptors = append(c.StreamInterceptors, interceptor)
 	}
 }
+
+// WithRetryPolicy 默认的重试策略（见 DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Parm) {
+		c.RetryPolicy = policy
+	}
+}
+
+// WithServiceRetryPolicy 调用 target 服务时使用的重试策略
+func WithServiceRetryPolicy(target string, policy RetryPolicy) Option {
+	return func(c *Parm) {
+		if c.RetryPolicies == nil {
+			c.RetryPolicies = make(map[string]RetryPolicy)
+		}
+		c.RetryPolicies[target] = policy
+	}
+}
+
+// WithMethodRetryPolicy 调用 target 服务的 methon 方法时使用的重试策略
+func WithMethodRetryPolicy(target, methon string, policy RetryPolicy) Option {
+	return func(c *Parm) {
+		if c.RetryPolicies == nil {
+			c.RetryPolicies = make(map[string]RetryPolicy)
+		}
+		c.RetryPolicies[retryPolicyKey(target, methon)] = policy
+	}
+}
+
+// WithRetryBudget 重试预算
+//
+//	ratio 重试次数与调用次数的最大比例
+//	minPerSecond 超出比例后每秒仍然允许的重试次数
+func WithRetryBudget(ratio float64, minPerSecond int) Option {
+	return func(c *Parm) {
+		c.RetryBudgetRatio = ratio
+		c.RetryBudgetMinPerSecond = minPerSecond
+	}
+}
+
+// WithCircuitBreaker 启用节点的熔断（见 DefaultBreakerPolicy），熔断的节点不会被负载均衡选取
+func WithCircuitBreaker(policy BreakerPolicy) Option {
+	return func(c *Parm) {
+		c.BreakerPolicy = policy
+	}
+}
+
+// WithBreakerListener 监听节点熔断状态的变更
+func WithBreakerListener(listener BreakerListener) Option {
+	return func(c *Parm) {
+		c.BreakerListener = listener
+	}
+}
+
+// WithTLS 使用 tls 连接服务端，caFile 为空时使用系统的根证书验证服务端的证书
+func WithTLS(caFile string) Option {
+	return func(c *Parm) {
+		c.TLS = true
+		c.TLSCAFile = caFile
+	}
+}
+
+// WithClientCert 向服务端提供客户端证书（mTLS，会同时启用 tls
+func WithClientCert(certFile, keyFile string) Option {
+	return func(c *Parm) {
+		c.TLS = true
+		c.TLSCertFile = certFile
+		c.TLSKeyFile = keyFile
+	}
+}
+
+// WithVerifyServiceName 验证服务端证书中包含服务发现中的服务名称（证书的 SAN 中需要包含服务名
+func WithVerifyServiceName() Option {
+	return func(c *Parm) {
+		c.TLSVerifyServiceName = true
+	}
+}
+
+// WithTLSReload 每隔 interval 检查一次证书文件，文件变更后新的连接使用新的证书
+func WithTLSReload(interval time.Duration) Option {
+	return func(c *Parm) {
+		c.TLSReloadInterval = interval
+	}
+}
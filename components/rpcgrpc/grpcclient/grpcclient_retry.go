@@ -0,0 +1,175 @@
+package grpcclient
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy rpc 调用的重试策略
+type RetryPolicy struct {
+	// 最大尝试次数（包含第一次调用），小于等于 1 时不重试
+	MaxAttempts int
+
+	// 第 n 次重试的等待时间为 InitialBackoff * BackoffMultiplier^(n-1)，不超过 MaxBackoff
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+
+	// 等待时间的随机抖动比例 0 ~ 1（如 0.2 表示在 ±20% 的范围内随机
+	Jitter float64
+
+	// 可以重试的 grpc 错误码，为空时只重试 codes.Unavailable
+	RetryableCodes []codes.Code
+
+	// 方法是否幂等，非幂等的方法只在请求没有发送出去时（获取连接失败，链接的节点已经退出）重试
+	Idempotent bool
+}
+
+var (
+	// DefaultRetryPolicy 幂等方法的默认重试策略
+	DefaultRetryPolicy = RetryPolicy{
+		MaxAttempts:       3,
+		InitialBackoff:    time.Millisecond * 50,
+		MaxBackoff:        time.Second,
+		BackoffMultiplier: 2,
+		Jitter:            0.2,
+		RetryableCodes:    []codes.Code{codes.Unavailable},
+		Idempotent:        true,
+	}
+)
+
+// retryable 调用失败后是否可以重试
+//
+//	sent 请求是否已经发送到了目标节点
+//	请求没有发送出去时只重试短暂的错误（获取连接失败，链接的节点已经退出），
+//	没有节点（ErrCantFindNode），没有可用的节点（ErrServiceNotAvailable）以及熔断（ErrBreakerOpen）直接返回
+func (rp *RetryPolicy) retryable(err error, sent bool) bool {
+
+	if !sent {
+		return errors.Is(err, ErrConnNotFound) || errors.Is(err, ErrLinkBroken)
+	}
+
+	if !rp.Idempotent {
+		return false
+	}
+
+	code := status.Code(err)
+	if len(rp.RetryableCodes) == 0 {
+		return code == codes.Unavailable
+	}
+
+	for _, c := range rp.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff 第 retry 次重试前的等待时间（retry 从 1 开始
+func (rp *RetryPolicy) backoff(retry int) time.Duration {
+
+	multiplier := rp.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(rp.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if rp.MaxBackoff > 0 && backoff > float64(rp.MaxBackoff) {
+		backoff = float64(rp.MaxBackoff)
+	}
+
+	if rp.Jitter > 0 {
+		backoff += backoff * rp.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	if backoff < 0 {
+		return 0
+	}
+
+	return time.Duration(backoff)
+}
+
+// retryPolicy 获取调用使用的重试策略，优先级 方法 > 服务 > 默认
+func (c *grpcClient) retryPolicy(target, methon string) RetryPolicy {
+
+	if rp, ok := c.parm.RetryPolicies[retryPolicyKey(target, methon)]; ok {
+		return rp
+	}
+
+	if rp, ok := c.parm.RetryPolicies[target]; ok {
+		return rp
+	}
+
+	return c.parm.RetryPolicy
+}
+
+// retryPolicyKey 方法重试策略的 key（服务的重试策略使用 target
+func retryPolicyKey(target, methon string) string {
+	return target + "|" + methon
+}
+
+// retryBudget 重试预算，限制重试请求占总请求的比例，防止在服务异常时产生重试风暴
+//
+//	每次调用存入 ratio 个令牌，每次重试消耗 1 个令牌；令牌不足时每秒仍然允许 minPerSecond 次重试
+type retryBudget struct {
+	ratio        float64
+	minPerSecond int
+
+	tokens float64
+
+	window time.Time
+	used   int
+
+	sync.Mutex
+}
+
+func newRetryBudget(ratio float64, minPerSecond int) *retryBudget {
+	return &retryBudget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+	}
+}
+
+// deposit 记录一次调用
+func (rb *retryBudget) deposit() {
+	rb.Lock()
+	defer rb.Unlock()
+
+	// 最多积累 10 / ratio 次调用的令牌
+	rb.tokens += rb.ratio
+	if rb.ratio > 0 && rb.tokens > 10 {
+		rb.tokens = 10
+	}
+}
+
+// withdraw 尝试消耗一次重试的预算
+func (rb *retryBudget) withdraw() bool {
+	rb.Lock()
+	defer rb.Unlock()
+
+	if rb.tokens >= 1 {
+		rb.tokens--
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(rb.window) >= time.Second {
+		rb.window = now
+		rb.used = 0
+	}
+
+	if rb.used < rb.minPerSecond {
+		rb.used++
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+package grpcclient
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicy(t *testing.T) {
+
+	rp := DefaultRetryPolicy
+
+	assert.True(t, rp.retryable(&CallError{Err: ErrConnNotFound}, false))
+	assert.True(t, rp.retryable(&CallError{Err: ErrLinkBroken}, false))
+	assert.False(t, rp.retryable(&CallError{Err: ErrCantFindNode}, false))
+	assert.False(t, rp.retryable(&NodeNotFoundError{}, false))
+	assert.False(t, rp.retryable(&CallError{Err: ErrServiceNotAvailable}, false))
+	assert.False(t, rp.retryable(&CallError{Err: ErrBreakerOpen}, false))
+	assert.False(t, rp.retryable(errors.New("unknown"), false))
+	assert.True(t, rp.retryable(status.Error(codes.Unavailable, ""), true))
+	assert.False(t, rp.retryable(status.Error(codes.Internal, ""), true))
+
+	rp.Idempotent = false
+	assert.True(t, rp.retryable(&CallError{Err: ErrConnNotFound}, false))
+	assert.False(t, rp.retryable(status.Error(codes.Unavailable, ""), true))
+
+	rp = RetryPolicy{
+		InitialBackoff:    time.Millisecond * 100,
+		MaxBackoff:        time.Millisecond * 300,
+		BackoffMultiplier: 2,
+	}
+	assert.Equal(t, rp.backoff(1), time.Millisecond*100)
+	assert.Equal(t, rp.backoff(2), time.Millisecond*200)
+	assert.Equal(t, rp.backoff(3), time.Millisecond*300)
+
+	rp.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := rp.backoff(1)
+		assert.True(t, backoff >= time.Millisecond*50 && backoff <= time.Millisecond*150)
+	}
+}
+
+func TestRetryPolicies(t *testing.T) {
+
+	service := RetryPolicy{MaxAttempts: 2}
+	methon := RetryPolicy{MaxAttempts: 3}
+
+	c := &grpcClient{parm: Parm{RetryPolicy: DefaultRetryPolicy}}
+	for _, opt := range []Option{
+		WithServiceRetryPolicy("base/login", service),
+		WithMethodRetryPolicy("base", "/login", methon),
+	} {
+		opt(&c.parm)
+	}
+
+	// 服务名和方法名拼接后相同时不会互相覆盖
+	assert.Equal(t, c.retryPolicy("base", "/login"), methon)
+	assert.Equal(t, c.retryPolicy("base/login", "/logout"), service)
+	assert.Equal(t, c.retryPolicy("base", "/logout"), DefaultRetryPolicy)
+}
+
+func TestRetryBudget(t *testing.T) {
+
+	rb := newRetryBudget(0.5, 1)
+
+	for i := 0; i < 4; i++ {
+		rb.deposit()
+	}
+
+	// 4 次调用积累 2 次重试，另外每秒允许 1 次
+	assert.True(t, rb.withdraw())
+	assert.True(t, rb.withdraw())
+	assert.True(t, rb.withdraw())
+	assert.False(t, rb.withdraw())
+}
+
+func TestInvokeRetry(t *testing.T) {
+
+	fs := &flakyServer{code: codes.Unavailable}
+
+	policy := DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond * 10
+
+	tc := newTestCluster(t, WithRetryPolicy(policy))
+	tc.serve("retry", fs)
+
+	tests := []struct {
+		name  string
+		fails int32
+		code  codes.Code
+		calls int32
+		err   codes.Code
+	}{
+		{"recover", 2, codes.Unavailable, 3, codes.OK},
+		{"exhausted", 5, codes.Unavailable, 3, codes.Unavailable},
+		{"not retryable", 1, codes.InvalidArgument, 1, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&fs.calls, 0)
+		atomic.StoreInt32(&fs.fails, tt.fails)
+		fs.code = tt.code
+
+		_, err := tc.call("retry", tt.name)
+		assert.Equal(t, Code(err), tt.err, tt.name)
+		assert.Equal(t, atomic.LoadInt32(&fs.calls), tt.calls, tt.name)
+	}
+
+	// 服务没有节点时不会重试
+	begin := time.Now()
+	_, err := tc.call("retry_unknown", "")
+	assert.True(t, errors.Is(err, ErrCantFindNode))
+	assert.Less(t, time.Since(begin), policy.InitialBackoff)
+}
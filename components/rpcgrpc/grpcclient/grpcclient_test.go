@@ -3,6 +3,8 @@ package grpcclient
 import (
 	"context"
 	"errors"
+	"net"
+	"os"
 	"testing"
 	"time"
 
@@ -36,11 +38,21 @@ func (rs *rpcServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.R
 	return out, err
 }
 
+// redisReachable 本地的 redis 是否可用，不可用时跳过依赖 redis 的测试（其他测试使用 pubsubmemory
+var redisReachable bool
+
 func TestMain(m *testing.M) {
 	mock.Init()
 
+	conn, err := net.DialTimeout("tcp", mock.RedisAddr, time.Second)
+	if err != nil {
+		os.Exit(m.Run())
+	}
+	conn.Close()
+	redisReachable = true
+
 	log := blog.BuildWithOption()
-	redisclient := bredis.BuildWithOption(&redis.Options{Addr: "127.0.0.1:6379"})
+	redisclient := bredis.BuildWithOption(&redis.Options{Addr: mock.RedisAddr})
 
 	ps := pubsubredis.BuildWithOption(
 		meta.ServiceInfo{ID: "", Name: ""},
@@ -79,9 +91,12 @@ func TestMain(m *testing.M) {
 }
 
 func TestInvoke(t *testing.T) {
+	if !redisReachable {
+		t.Skip("redis is not reachable")
+	}
 
 	log := blog.BuildWithOption()
-	redisclient := bredis.BuildWithOption(&redis.Options{Addr: "127.0.0.1:6379"})
+	redisclient := bredis.BuildWithOption(&redis.Options{Addr: mock.RedisAddr})
 
 	ps := pubsubredis.BuildWithOption(
 		meta.ServiceInfo{ID: "", Name: ""},
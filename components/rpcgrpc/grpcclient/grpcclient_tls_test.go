@@ -0,0 +1,119 @@
+package grpcclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// newTestCert 生成证书文件 dir/name.crt dir/name.key，ca 为空时生成自签名的 CA
+func newTestCert(t *testing.T, dir, name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, hosts ...string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		ca, caKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	assert.Nil(t, err)
+	cert, _ := x509.ParseCertificate(der)
+
+	keyder, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyder}), 0600))
+
+	return cert, key
+}
+
+func TestInvokeTLS(t *testing.T) {
+
+	dir := t.TempDir()
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	ca, caKey := newTestCert(t, dir, "ca", nil, nil)
+	newTestCert(t, dir, "server", ca, caKey, "tls_ok", "tls_nocert", "127.0.0.1")
+	newTestCert(t, dir, "client", ca, caKey, "client")
+
+	// 服务端要求客户端证书（mTLS
+	cert, err := tls.LoadX509KeyPair(file("server.crt"), file("server.key"))
+	assert.Nil(t, err)
+	cas := x509.NewCertPool()
+	cas.AddCert(ca)
+	creds := grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    cas,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}))
+
+	tests := []struct {
+		name string
+		opts []Option
+		ok   bool
+	}{
+		{"tls_ok", []Option{
+			WithTLS(file("ca.crt")),
+			WithClientCert(file("client.crt"), file("client.key")),
+			WithVerifyServiceName(),
+		}, true},
+		{"tls_nocert", []Option{
+			WithTLS(file("ca.crt")),
+		}, false},
+		{"tls_name", []Option{
+			WithTLS(file("ca.crt")),
+			WithClientCert(file("client.crt"), file("client.key")),
+			WithVerifyServiceName(),
+		}, false},
+	}
+
+	for _, tt := range tests {
+		fs := &flakyServer{}
+
+		tc := newTestCluster(t, append(tt.opts, WithPoolCapacity(1))...)
+		tc.serve(tt.name, fs, creds)
+
+		_, err := tc.call(tt.name, "", WithCallTimeout(time.Second))
+		assert.Equal(t, err == nil, tt.ok, tt.name, err)
+		assert.Equal(t, atomic.LoadInt32(&fs.calls) == 1, tt.ok, tt.name)
+	}
+}
@@ -0,0 +1,124 @@
+// 实现文件 基于 route.proto 实现的路由网关，将 RouteReq 中的请求转发到目标服务
+package grpcgateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// rawCodec 直接发送和接收序列化后的消息（网关不需要知道消息的类型
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case *[]byte:
+		return *b, nil
+	}
+	return nil, fmt.Errorf("raw codec marshal unsupported type %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec unmarshal unsupported type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name 和 proto 编码的 content-subtype 一致，服务端使用 proto 编码解析消息
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+type gateway struct {
+	proto.ListenServer
+
+	info   meta.ServiceInfo
+	parm   Parm
+	log    *blog.Logger
+	client module.IClient
+
+	services map[string]bool
+}
+
+// BuildWithOption 构建路由网关，通过 client 将 RouteReq.ReqBody 转发到 RouteReq.Nod 服务的 RouteReq.Service 方法
+//
+//	grpcserver.RegisterHandler(func(srv *grpc.Server) {
+//		proto.RegisterListenServer(srv, grpcgateway.BuildWithOption(info, log, director.Client()))
+//	})
+func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, client module.IClient, opts ...Option) proto.ListenServer {
+
+	p := DefaultGatewayParm
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	g := &gateway{
+		info:     info,
+		parm:     p,
+		log:      log,
+		client:   client,
+		services: make(map[string]bool),
+	}
+
+	for _, service := range p.Services {
+		g.services[service] = true
+	}
+
+	return g
+}
+
+// Routing 转发请求，RouteReq.Meta 作为 grpc 的元数据传递给目标服务
+func (g *gateway) Routing(ctx context.Context, req *proto.RouteReq) (*proto.RouteRes, error) {
+
+	if req.Nod == "" || req.Service == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "route nod and service required")
+	}
+
+	if len(g.services) != 0 && !g.services[req.Nod] {
+		return nil, status.Errorf(codes.PermissionDenied, "route to service %s not allowed", req.Nod)
+	}
+
+	methon := req.Service
+	if !strings.HasPrefix(methon, "/") {
+		methon = "/" + methon
+	}
+
+	if len(req.Meta) != 0 {
+		kv := make([]string, 0, len(req.Meta)*2)
+		for _, h := range req.Meta {
+			kv = append(kv, h.Key, h.Val)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, kv...)
+	}
+
+	if g.parm.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.parm.Timeout)
+		defer cancel()
+	}
+
+	opts := append([]interface{}{grpc.ForceCodec(rawCodec{})}, g.parm.CallOpts...)
+
+	var reply []byte
+	err := g.client.Invoke(ctx, req.Nod, methon, req.Token, req.ReqBody, &reply, opts...)
+	if err != nil {
+		g.log.Warnf("[braid.gateway] routing err %s, nod = %s, service = %s, token = %s", err.Error(), req.Nod, methon, req.Token)
+		return nil, err
+	}
+
+	return &proto.RouteRes{ResBody: reply}, nil
+}
@@ -0,0 +1,45 @@
+package grpcgateway
+
+import (
+	"time"
+)
+
+// Parm 网关配置项
+type Parm struct {
+	// 允许转发的目标服务，为空时允许转发到所有的服务
+	Services []string
+
+	// 转发调用的超时时间，为 0 时只受请求的 context 约束
+	Timeout time.Duration
+
+	// 转发调用时附加的调用选项（grpcclient.CallOption, grpc.CallOption
+	CallOpts []interface{}
+}
+
+var (
+	DefaultGatewayParm = Parm{}
+)
+
+// Option config wraps
+type Option func(*Parm)
+
+// WithServices 只允许转发到 services 中的服务
+func WithServices(services ...string) Option {
+	return func(c *Parm) {
+		c.Services = append(c.Services, services...)
+	}
+}
+
+// WithTimeout 转发调用的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Parm) {
+		c.Timeout = timeout
+	}
+}
+
+// WithCallOptions 转发调用时附加的调用选项（如 grpcclient.WithCallStrategy
+func WithCallOptions(opts ...interface{}) Option {
+	return func(c *Parm) {
+		c.CallOpts = append(c.CallOpts, opts...)
+	}
+}
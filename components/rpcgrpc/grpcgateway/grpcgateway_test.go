@@ -0,0 +1,176 @@
+package grpcgateway
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/pojol/braid-go/module"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// echoServer 目标服务，返回请求中的 ReqBody 以及收到的元数据 uid
+type echoServer struct {
+	proto.ListenServer
+}
+
+func (es *echoServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.RouteRes, error) {
+	res := &proto.RouteRes{ResBody: req.ReqBody}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("uid")) != 0 {
+		res.ResBody = append(res.ResBody, []byte("-"+md.Get("uid")[0])...)
+	}
+
+	if string(req.ReqBody) == "unavailable" {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+
+	return res, nil
+}
+
+// connClient 直接使用连接发起调用的 client，记录最后一次调用的参数
+type connClient struct {
+	module.IClient
+
+	conn *grpc.ClientConn
+
+	target   string
+	methon   string
+	token    string
+	opts     []interface{}
+	deadline bool
+}
+
+func (cc *connClient) Invoke(ctx context.Context, target, methon, token string, args, reply interface{}, opts ...interface{}) error {
+	cc.target, cc.methon, cc.token, cc.opts = target, methon, token, opts
+	_, cc.deadline = ctx.Deadline()
+
+	var grpcOpts []grpc.CallOption
+	for _, opt := range opts {
+		if o, ok := opt.(grpc.CallOption); ok {
+			grpcOpts = append(grpcOpts, o)
+		}
+	}
+
+	return cc.conn.Invoke(ctx, methon, args, reply, grpcOpts...)
+}
+
+func newConnClient(t *testing.T) *connClient {
+
+	lis := bufconn.Listen(1024 * 1024)
+
+	srv := grpc.NewServer()
+	proto.RegisterListenServer(srv, &echoServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &connClient{conn: conn}
+}
+
+func TestRawCodec(t *testing.T) {
+
+	byt, err := rawCodec{}.Marshal([]byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, byt, []byte("a"))
+
+	b := []byte("b")
+	byt, err = rawCodec{}.Marshal(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, byt, []byte("b"))
+
+	_, err = rawCodec{}.Marshal("c")
+	assert.NotNil(t, err)
+
+	var reply []byte
+	assert.Nil(t, rawCodec{}.Unmarshal([]byte("d"), &reply))
+	assert.Equal(t, reply, []byte("d"))
+	assert.NotNil(t, rawCodec{}.Unmarshal([]byte("d"), reply))
+}
+
+func TestRouting(t *testing.T) {
+
+	cc := newConnClient(t)
+	info := meta.ServiceInfo{ID: "TestRouting", Name: "gateway"}
+
+	g := BuildWithOption(info, blog.BuildWithDefaultOption(), cc,
+		WithServices("base"),
+		WithTimeout(time.Second),
+		WithCallOptions("call_opt"),
+	)
+
+	body, err := (&proto.RouteReq{ReqBody: []byte("hello")}).Marshal()
+	assert.Nil(t, err)
+
+	res, err := g.Routing(context.TODO(), &proto.RouteReq{
+		Nod:     "base",
+		Service: "proto.listen/routing",
+		Token:   "token",
+		ReqBody: body,
+		Meta:    []*proto.Header{{Key: "uid", Val: "1001"}},
+	})
+	assert.Nil(t, err)
+
+	// 转发的方法补全前缀，元数据和超时传递给目标服务
+	assert.Equal(t, cc.target, "base")
+	assert.Equal(t, cc.methon, "/proto.listen/routing")
+	assert.Equal(t, cc.token, "token")
+	assert.Equal(t, cc.opts[1], "call_opt")
+	assert.True(t, cc.deadline)
+
+	reply := &proto.RouteRes{}
+	assert.Nil(t, reply.Unmarshal(res.ResBody))
+	assert.Equal(t, string(reply.ResBody), "hello-1001")
+
+	// 目标服务返回的错误直接返回给调用方
+	body, _ = (&proto.RouteReq{ReqBody: []byte("unavailable")}).Marshal()
+	_, err = g.Routing(context.TODO(), &proto.RouteReq{Nod: "base", Service: "/proto.listen/routing", ReqBody: body})
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+}
+
+func TestRoutingReject(t *testing.T) {
+
+	cc := newConnClient(t)
+	info := meta.ServiceInfo{ID: "TestRoutingReject", Name: "gateway"}
+
+	g := BuildWithOption(info, blog.BuildWithDefaultOption(), cc, WithServices("base"))
+
+	_, err := g.Routing(context.TODO(), &proto.RouteReq{Service: "/proto.listen/routing"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+
+	_, err = g.Routing(context.TODO(), &proto.RouteReq{Nod: "base"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+
+	_, err = g.Routing(context.TODO(), &proto.RouteReq{Nod: "login", Service: "/proto.listen/routing"})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+
+	// 被拒绝的请求不会转发
+	assert.Equal(t, cc.target, "")
+
+	// 没有限制目标服务，也没有设置超时时间
+	g = BuildWithOption(info, blog.BuildWithDefaultOption(), cc)
+
+	body, _ := (&proto.RouteReq{ReqBody: []byte("login")}).Marshal()
+	_, err = g.Routing(context.TODO(), &proto.RouteReq{Nod: "login", Service: "/proto.listen/routing", ReqBody: body})
+	assert.Nil(t, err)
+	assert.Equal(t, cc.target, "login")
+	assert.False(t, cc.deadline)
+}
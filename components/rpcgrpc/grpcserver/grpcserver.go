@@ -2,15 +2,22 @@
 package grpcserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
+	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/internal/tlsreload"
 	"github.com/pojol/braid-go/module"
 	"github.com/pojol/braid-go/module/meta"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var (
@@ -26,41 +33,107 @@ type grpcServer struct {
 	listen net.Listener
 	log    *blog.Logger
 	parm   Parm
+
+	// 是否正在处理请求
+	serving int32
+	// Serve 退出时的错误
+	serveErr atomic.Value
+
+	tls *tlsreload.Reloader
+
+	// consul 自注册（没有设置 Registry 时为空
+	registrar *registrar
+
+	// grpc.health.v1.Health 服务（没有设置 WithHealth 或 WithRegisterGRPCCheck 时为空
+	health *health.Server
+
+	// 并发限制（没有启用时为空
+	limiter *limiterGroup
 }
 
 func BuildWithOption(info meta.ServiceInfo, log *blog.Logger, opts ...Option) module.IServer {
 
-	p := Parm{
-		ListenAddr: ":14222",
-	}
+	p := DefaultServerParm
 
 	for _, opt := range opts {
 		opt(&p)
 	}
 
-	var rpcserver *grpc.Server
+	var serveropts []grpc.ServerOption
 
-	if len(p.UnaryInterceptors) != 0 {
-		rpcserver = grpc.NewServer(grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(p.UnaryInterceptors...)))
-	} else {
-		rpcserver = grpc.NewServer()
+	unary, stream := p.UnaryInterceptors, p.StreamInterceptors
+
+	// 并发限制在最外层，超过上限的请求不会经过其他的拦截器
+	var limiter *limiterGroup
+	if p.LimitPolicy.enabled() {
+		limiter = newLimiterGroup(p.LimitPolicy, p.LimitListener)
+		unary = append([]grpc.UnaryServerInterceptor{limiter.unaryInterceptor()}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{limiter.streamInterceptor()}, stream...)
 	}
 
+	if len(unary) != 0 {
+		serveropts = append(serveropts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unary...)))
+	}
+
+	if len(stream) != 0 {
+		serveropts = append(serveropts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(stream...)))
+	}
+
+	var reloader *tlsreload.Reloader
+	if p.TLSCertFile != "" {
+		reloader = tlsreload.New(p.TLSCertFile, p.TLSKeyFile, p.TLSClientCAFile)
+		serveropts = append(serveropts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig())))
+	}
+
+	rpcserver := grpc.NewServer(serveropts...)
+
 	if p.Handler == nil {
 		panic(fmt.Errorf("grpc server handler not set"))
 	}
 
-	return &grpcServer{
+	s := &grpcServer{
 		info: info,
 		parm: p,
 		log:  log,
 		rpc:  rpcserver,
+		tls:  reloader,
+
+		limiter: limiter,
+	}
+
+	if p.healthEnabled() {
+		s.health = registerHealth(s)
+	}
+
+	if p.Reflection {
+		registerReflection(s)
+	}
+
+	if p.Registry != nil {
+		s.registrar = newRegistrar(s)
 	}
 
+	return s
+
 }
 
 func (s *grpcServer) Init() error {
 
+	if s.tls != nil {
+		err := s.tls.Load()
+		if err != nil {
+			return fmt.Errorf("%v [GRPC] server load tls err %w", s.info.Name, err)
+		}
+
+		s.tls.Watch(s.parm.TLSReloadInterval, func(err error) {
+			if err != nil {
+				s.log.Warnf("[GRPC] server reload tls err %s", err.Error())
+			} else {
+				s.log.Infof("[GRPC] server reload tls %v", s.parm.TLSCertFile)
+			}
+		})
+	}
+
 	rpcListen, err := net.Listen("tcp", s.parm.ListenAddr)
 	if err != nil {
 		return fmt.Errorf("%v [GRPC] server check error %v [%v]", s.info.Name, "tcp", s.parm.ListenAddr)
@@ -70,6 +143,11 @@ func (s *grpcServer) Init() error {
 
 	s.listen = rpcListen
 
+	// regist rpc handler
+	s.parm.Handler(s.rpc)
+	// 在开始侦听之前，所有用户注册的服务都处于 NOT_SERVING 状态
+	s.setServing(healthpb.HealthCheckResponse_NOT_SERVING)
+
 	return nil
 }
 
@@ -81,23 +159,107 @@ func (s *grpcServer) Server() interface{} {
 // Run 运行
 func (s *grpcServer) Run() {
 
-	// regist rpc handler
-	s.parm.Handler(s.rpc)
+	atomic.StoreInt32(&s.serving, 1)
+	s.setServing(healthpb.HealthCheckResponse_SERVING)
 
 	go func() {
 		if err := s.rpc.Serve(s.listen); err != nil {
 			s.log.Errf("[GRPC] server serving err %s", err.Error())
+			s.serveErr.Store(err)
+			s.setServing(healthpb.HealthCheckResponse_NOT_SERVING)
 		}
+		atomic.StoreInt32(&s.serving, 0)
 	}()
 
+	if s.registrar != nil {
+		s.registrar.Run()
+	}
+
+}
+
+// Health 正在侦听时就绪，Serve 异常退出后不再存活
+func (s *grpcServer) Health(ctx context.Context) module.HealthStatus {
+	if err, ok := s.serveErr.Load().(error); ok {
+		return module.HealthStatus{Message: "serving err " + err.Error()}
+	}
+
+	if atomic.LoadInt32(&s.serving) == 0 {
+		return module.HealthStatus{Live: true, Message: "not serving"}
+	}
+
+	msg := "listen " + s.parm.ListenAddr
+	if s.registrar != nil && !s.registrar.Registered() {
+		msg += ", not registered"
+	}
+	if s.limiter != nil {
+		msg += fmt.Sprintf(", shed %d", s.limiter.shed())
+	}
+
+	return module.HealthStatus{Live: true, Ready: true, Message: msg}
+}
+
+// LimitStats 各个方法的并发限制状态（见 Limiter
+func (s *grpcServer) LimitStats() []LimitStats {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.stats()
 }
 
 // Close 退出处理
 func (s *grpcServer) Close() {
-	s.log.Infof("grpc-server closed")
-	if s.parm.GracefulStop {
+	atomic.StoreInt32(&s.serving, 0)
+
+	defer s.log.Infof("grpc-server closed")
+
+	// 先从注册中心注销，避免新的请求被路由到正在退出的节点
+	if s.registrar != nil {
+		s.registrar.Close()
+	}
+	// 排空请求期间 health 服务返回 NOT_SERVING，之后状态不会再变更
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+
+	if s.tls != nil {
+		s.tls.Close()
+	}
+
+	if !s.parm.GracefulStop {
+		s.rpc.Stop()
+		s.closeListen()
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
 		s.rpc.GracefulStop()
-	} else {
+		close(done)
+	}()
+
+	if s.parm.GracefulStopTimeout <= 0 {
+		<-done
+		s.closeListen()
+		return
+	}
+
+	timer := time.NewTimer(s.parm.GracefulStopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		s.log.Warnf("[GRPC] graceful stop timeout %v, force stop", s.parm.GracefulStopTimeout)
 		s.rpc.Stop()
+		<-done
+	}
+
+	s.closeListen()
+}
+
+// closeListen 没有运行（Run）的服务器不会由 grpc 关闭侦听
+func (s *grpcServer) closeListen() {
+	if s.listen != nil {
+		s.listen.Close()
 	}
 }
@@ -0,0 +1,42 @@
+package grpcserver
+
+import (
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// healthEnabled 是否注册 grpc.health.v1.Health 服务（WithHealth，或者使用 consul 的 grpc 检查时
+func (p *Parm) healthEnabled() bool {
+	return p.Health || (p.Registry != nil && p.RegisterCheck == CheckGRPC)
+}
+
+// registerHealth 注册 grpc.health.v1.Health 服务，Run 之前处于 NOT_SERVING 状态
+func registerHealth(s *grpcServer) *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s.rpc, hs)
+	return hs
+}
+
+// setServing 设置 server 以及所有用户注册的服务的状态（服务名为空时表示整个 server
+func (s *grpcServer) setServing(status healthpb.HealthCheckResponse_ServingStatus) {
+	if s.health == nil {
+		return
+	}
+
+	for name := range s.rpc.GetServiceInfo() {
+		if name == healthpb.Health_ServiceDesc.ServiceName || name == rpb.ServerReflection_ServiceDesc.ServiceName {
+			continue
+		}
+		s.health.SetServingStatus(name, status)
+	}
+
+	s.health.SetServingStatus("", status)
+}
+
+// registerReflection 注册 grpc 反射服务（grpcurl 等工具可以通过反射获取服务列表
+func registerReflection(s *grpcServer) {
+	reflection.Register(s.rpc)
+}
@@ -0,0 +1,282 @@
+package grpcserver
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LimitPolicy 按方法的自适应并发限制策略，InitialLimit 为 0 时不启用
+//
+//	请求的延迟没有超过 最小延迟 * Tolerance 时（gradient）逐步提高并发上限，延迟升高时按照比例降低，
+//	处理超时 or 下游资源耗尽时按照 Backoff 乘性降低（AIMD）；超过上限的请求直接返回 codes.ResourceExhausted
+type LimitPolicy struct {
+	// 初始的并发上限
+	InitialLimit int
+	// 并发上限的范围
+	MinLimit int
+	MaxLimit int
+
+	// 延迟的容忍度，请求的延迟超过 最小延迟 * Tolerance 时开始降低并发上限
+	Tolerance float64
+	// 最小延迟（无负载时的延迟）的衰减窗口，超过窗口没有更低的样本时，最小延迟按照 Smoothing 向当前的延迟靠近
+	MinRTTWindow time.Duration
+	// 每次调整时新的上限所占的比例（0 ~ 1
+	Smoothing float64
+	// 请求被丢弃（DeadlineExceeded, ResourceExhausted）时上限乘以 Backoff
+	Backoff float64
+}
+
+var (
+	// DefaultLimitPolicy 默认的并发限制策略
+	DefaultLimitPolicy = LimitPolicy{
+		InitialLimit: 20,
+		MinLimit:     1,
+		MaxLimit:     1000,
+		Tolerance:    2,
+		MinRTTWindow: time.Second * 10,
+		Smoothing:    0.2,
+		Backoff:      0.9,
+	}
+)
+
+// LimitListener 请求被拒绝时的回调（在请求的处理流程中同步调用，不要执行耗时的操作
+//
+//	method 完整的方法名，limit 当前的并发上限，shed 这个方法累计被拒绝的请求数
+type LimitListener func(method string, limit int, shed uint64)
+
+// LimitStats 单个方法的并发限制状态
+type LimitStats struct {
+	Method   string
+	Limit    int
+	Inflight int
+	// 累计被拒绝的请求数
+	Shed uint64
+	// 当前作为基准的最小延迟
+	MinRTT time.Duration
+}
+
+// Limiter 获取 server 各个方法的并发限制状态（module.IServer 断言为 Limiter，没有开启并发限制时返回空
+type Limiter interface {
+	LimitStats() []LimitStats
+}
+
+func (lp *LimitPolicy) enabled() bool {
+	return lp.InitialLimit > 0
+}
+
+// limit 单个方法的并发限制
+type limit struct {
+	limit    float64
+	inflight int
+	shed     uint64
+
+	minRTT   time.Duration
+	minRTTAt time.Time
+}
+
+// limiterGroup 按照方法管理并发限制
+type limiterGroup struct {
+	policy   LimitPolicy
+	listener LimitListener
+
+	limits map[string]*limit
+
+	sync.Mutex
+}
+
+func newLimiterGroup(policy LimitPolicy, listener LimitListener) *limiterGroup {
+	if policy.MinLimit <= 0 {
+		policy.MinLimit = 1
+	}
+	if policy.MaxLimit < policy.InitialLimit {
+		policy.MaxLimit = policy.InitialLimit
+	}
+	if policy.Tolerance < 1 {
+		policy.Tolerance = DefaultLimitPolicy.Tolerance
+	}
+	if policy.MinRTTWindow <= 0 {
+		policy.MinRTTWindow = DefaultLimitPolicy.MinRTTWindow
+	}
+	if policy.Smoothing <= 0 || policy.Smoothing > 1 {
+		policy.Smoothing = DefaultLimitPolicy.Smoothing
+	}
+	if policy.Backoff <= 0 || policy.Backoff >= 1 {
+		policy.Backoff = DefaultLimitPolicy.Backoff
+	}
+
+	return &limiterGroup{
+		policy:   policy,
+		listener: listener,
+		limits:   make(map[string]*limit),
+	}
+}
+
+func (lg *limiterGroup) get(method string) *limit {
+	l, ok := lg.limits[method]
+	if !ok {
+		l = &limit{limit: float64(lg.policy.InitialLimit)}
+		lg.limits[method] = l
+	}
+	return l
+}
+
+// acquire 获取执行的许可，达到并发上限时返回 false
+func (lg *limiterGroup) acquire(method string) bool {
+	lg.Lock()
+
+	l := lg.get(method)
+	if l.inflight < int(l.limit) {
+		l.inflight++
+		lg.Unlock()
+		return true
+	}
+
+	l.shed++
+	cur, shed := int(l.limit), l.shed
+	lg.Unlock()
+
+	if lg.listener != nil {
+		lg.listener(method, cur, shed)
+	}
+
+	return false
+}
+
+// release 请求处理完成，根据请求的延迟以及结果调整并发上限
+func (lg *limiterGroup) release(method string, rtt time.Duration, err error) {
+	lg.Lock()
+	defer lg.Unlock()
+
+	l := lg.get(method)
+	inflight := l.inflight
+	l.inflight--
+
+	p := &lg.policy
+
+	switch status.Code(err) {
+	case codes.Canceled:
+		// 调用方取消的请求不作为延迟的样本
+		return
+	case codes.DeadlineExceeded, codes.ResourceExhausted:
+		l.limit = math.Max(float64(p.MinLimit), l.limit*p.Backoff)
+		return
+	}
+
+	// 没有延迟样本（如流
+	if rtt <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if l.minRTT == 0 || rtt < l.minRTT {
+		l.minRTT = rtt
+		l.minRTTAt = now
+	} else if now.Sub(l.minRTTAt) > p.MinRTTWindow {
+		// 只按照比例靠近当前的延迟，避免过载时的延迟直接成为新的基准
+		l.minRTT += time.Duration(float64(rtt-l.minRTT) * p.Smoothing)
+		l.minRTTAt = now
+	}
+
+	gradient := math.Max(0.5, math.Min(1, float64(l.minRTT)*p.Tolerance/float64(rtt)))
+	next := l.limit*gradient + math.Sqrt(l.limit)
+
+	// 并发数远低于上限时没有必要继续提高上限
+	if next > l.limit && float64(inflight) < l.limit/2 {
+		return
+	}
+
+	next = l.limit*(1-p.Smoothing) + next*p.Smoothing
+	l.limit = math.Max(float64(p.MinLimit), math.Min(float64(p.MaxLimit), next))
+}
+
+// shed 累计被拒绝的请求数
+func (lg *limiterGroup) shed() uint64 {
+	lg.Lock()
+	defer lg.Unlock()
+
+	var total uint64
+	for _, l := range lg.limits {
+		total += l.shed
+	}
+	return total
+}
+
+// stats 所有方法的并发限制状态（按照方法名排序
+func (lg *limiterGroup) stats() []LimitStats {
+	lg.Lock()
+	defer lg.Unlock()
+
+	lst := make([]LimitStats, 0, len(lg.limits))
+	for method, l := range lg.limits {
+		lst = append(lst, LimitStats{
+			Method:   method,
+			Limit:    int(l.limit),
+			Inflight: l.inflight,
+			Shed:     l.shed,
+			MinRTT:   l.minRTT,
+		})
+	}
+
+	sort.Slice(lst, func(i, j int) bool {
+		return lst[i].Method < lst[j].Method
+	})
+
+	return lst
+}
+
+// exempt health 和反射服务不受并发限制（探针需要在过载时也能得到响应
+func exempt(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+func (lg *limiterGroup) reject(method string) error {
+	return status.Errorf(codes.ResourceExhausted, "concurrency limit exceeded, method %v", method)
+}
+
+func (lg *limiterGroup) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		if exempt(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if !lg.acquire(info.FullMethod) {
+			return nil, lg.reject(info.FullMethod)
+		}
+
+		// handler panic 时也需要释放许可
+		begin := time.Now()
+		defer func() {
+			lg.release(info.FullMethod, time.Since(begin), err)
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor 流在整个生命周期内占用一个并发许可（流的持续时间不作为延迟的样本
+func (lg *limiterGroup) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if exempt(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		if !lg.acquire(info.FullMethod) {
+			return lg.reject(info.FullMethod)
+		}
+
+		defer func() {
+			lg.release(info.FullMethod, 0, err)
+		}()
+
+		return handler(srv, ss)
+	}
+}
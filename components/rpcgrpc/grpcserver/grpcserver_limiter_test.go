@@ -0,0 +1,184 @@
+package grpcserver
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterAdapt(t *testing.T) {
+
+	policy := DefaultLimitPolicy
+	policy.InitialLimit = 10
+	policy.MaxLimit = 100
+
+	var shed uint64
+	var shedLimit int
+	lg := newLimiterGroup(policy, func(method string, limit int, n uint64) {
+		shed, shedLimit = n, limit
+	})
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, lg.acquire("/a"))
+	}
+	assert.False(t, lg.acquire("/a"))
+	assert.False(t, lg.acquire("/a"))
+	assert.Equal(t, shed, uint64(2))
+	assert.Equal(t, shedLimit, 10)
+	assert.Equal(t, lg.shed(), uint64(2))
+
+	// 其他方法不受影响
+	assert.True(t, lg.acquire("/b"))
+	lg.release("/b", time.Millisecond, nil)
+
+	// 满负载并且延迟稳定时提高上限
+	for i := 0; i < 10; i++ {
+		lg.release("/a", time.Millisecond*10, nil)
+		assert.True(t, lg.acquire("/a"))
+	}
+	grown := lg.get("/a").limit
+	assert.Greater(t, grown, 10.0)
+
+	// 延迟升高时降低上限
+	for i := 0; i < 5; i++ {
+		lg.release("/a", time.Millisecond*100, nil)
+	}
+	lowered := lg.get("/a").limit
+	assert.Less(t, lowered, grown)
+
+	// 处理超时时乘性降低
+	lg.release("/a", time.Millisecond*10, status.Error(codes.DeadlineExceeded, "timeout"))
+	assert.InDelta(t, lg.get("/a").limit, lowered*policy.Backoff, 0.001)
+
+	// 上限不会低于 MinLimit
+	for lg.get("/a").inflight > 0 {
+		lg.release("/a", 0, status.Error(codes.ResourceExhausted, "exhausted"))
+	}
+	for i := 0; i < 100; i++ {
+		assert.True(t, lg.acquire("/a"))
+		lg.release("/a", 0, status.Error(codes.ResourceExhausted, "exhausted"))
+	}
+	assert.Equal(t, lg.get("/a").limit, float64(policy.MinLimit))
+}
+
+func TestLimiterMinRTT(t *testing.T) {
+
+	policy := DefaultLimitPolicy
+	policy.MinRTTWindow = time.Millisecond
+
+	lg := newLimiterGroup(policy, nil)
+
+	assert.True(t, lg.acquire("/a"))
+	lg.release("/a", time.Millisecond*10, nil)
+	assert.Equal(t, lg.get("/a").minRTT, time.Millisecond*10)
+
+	// 超过窗口后只按照比例靠近过载时的延迟
+	time.Sleep(time.Millisecond * 2)
+	assert.True(t, lg.acquire("/a"))
+	lg.release("/a", time.Millisecond*110, nil)
+	assert.Equal(t, lg.get("/a").minRTT, time.Millisecond*30)
+
+	// 更低的延迟直接作为新的基准
+	assert.True(t, lg.acquire("/a"))
+	lg.release("/a", time.Millisecond*5, nil)
+	assert.Equal(t, lg.get("/a").minRTT, time.Millisecond*5)
+}
+
+func TestLimiterPanic(t *testing.T) {
+
+	lg := newLimiterGroup(DefaultLimitPolicy, nil)
+	interceptor := lg.unaryInterceptor()
+
+	func() {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+
+		interceptor(context.TODO(), nil, &grpc.UnaryServerInfo{FullMethod: "/a"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("handler panic")
+		})
+	}()
+
+	// handler panic 后许可已经被释放
+	stats := lg.stats()
+	assert.Equal(t, len(stats), 1)
+	assert.Equal(t, stats[0].Method, "/a")
+	assert.Equal(t, stats[0].Inflight, 0)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+
+	var shed int32
+
+	s := BuildWithOption(
+		meta.ServiceInfo{
+			Name: "servergrpctest",
+			ID:   uuid.New().String(),
+		},
+		blog.BuildWithOption(),
+		WithListen(":14117"),
+		WithHealth(),
+		WithConcurrencyLimit(LimitPolicy{InitialLimit: 2, MaxLimit: 2}),
+		WithLimitListener(func(method string, limit int, n uint64) {
+			atomic.StoreInt32(&shed, int32(n))
+		}),
+		RegisterHandler(func(srv *grpc.Server) {
+			proto.RegisterListenServer(srv, &rpcServer{})
+		}),
+	)
+
+	assert.Equal(t, s.Init(), nil)
+	s.Run()
+	defer s.Close()
+
+	conn, err := grpc.Dial(":14117", grpc.WithInsecure())
+	assert.Equal(t, err, nil)
+	defer conn.Close()
+
+	var wg sync.WaitGroup
+	var exhausted int32
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := conn.Invoke(context.TODO(), "/proto.listen/routing", &proto.RouteReq{Service: "slow"}, new(proto.RouteRes))
+			if status.Code(err) == codes.ResourceExhausted {
+				atomic.AddInt32(&exhausted, 1)
+			}
+		}()
+	}
+
+	// 过载时 health 检查不受限制
+	time.Sleep(time.Millisecond * 50)
+	res, err := healthpb.NewHealthClient(conn).Check(context.TODO(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Status, healthpb.HealthCheckResponse_SERVING)
+
+	wg.Wait()
+	assert.Equal(t, atomic.LoadInt32(&exhausted), int32(1))
+	assert.Equal(t, atomic.LoadInt32(&shed), int32(1))
+	assert.Contains(t, s.(*grpcServer).Health(context.TODO()).Message, "shed 1")
+
+	stats := s.(Limiter).LimitStats()
+	assert.Equal(t, len(stats), 1)
+	assert.Equal(t, stats[0].Method, "/proto.listen/Routing")
+	assert.Equal(t, stats[0].Limit, 2)
+	assert.Equal(t, stats[0].Inflight, 0)
+	assert.Equal(t, stats[0].Shed, uint64(1))
+
+	// 并发数降低后恢复
+	err = conn.Invoke(context.TODO(), "/proto.listen/routing", &proto.RouteReq{Service: "test"}, new(proto.RouteRes))
+	assert.Equal(t, err, nil)
+}
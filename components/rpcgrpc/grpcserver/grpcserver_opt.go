@@ -1,6 +1,8 @@
 package grpcserver
 
 import (
+	"time"
+
 	"google.golang.org/grpc"
 )
 
@@ -15,9 +17,58 @@ type Parm struct {
 
 	Handler RegistHandler
 
+	// 关闭时停止接收新的请求并等待处理中的请求完成（默认开启，关闭时直接断开处理中的请求
 	GracefulStop bool
+
+	// 优雅退出时等待处理中请求的最长时间，超时后强制关闭（为 0 时一直等待，默认 5s
+	GracefulStopTimeout time.Duration
+
+	// tls 证书文件，为空时不启用 tls
+	TLSCertFile string
+	TLSKeyFile  string
+	// 验证客户端证书使用的 CA 文件，设置后要求客户端提供证书（mTLS
+	TLSClientCAFile string
+	// 检查证书文件变更的间隔，为 0 时不热加载
+	TLSReloadInterval time.Duration
+
+	// 按方法的自适应并发限制（InitialLimit 为 0 时不启用
+	LimitPolicy   LimitPolicy
+	LimitListener LimitListener
+
+	// 注册 grpc.health.v1.Health 服务（使用 CheckGRPC 注册到 consul 时总是会注册
+	Health bool
+	// 注册 grpc 反射服务
+	Reflection bool
+
+	// 注册中心（*bconsul.Client），设置后 server 在 Run 时将自身注册到 consul，Close 时注销
+	Registry Registry
+	// 注册的地址（host:port），为空时使用本机 ip 和侦听的端口
+	RegisterAddress string
+	// 注册时附带的标签（总是包含 RegisterTag
+	RegisterTags []string
+	// 节点的权重，写入 consul 元数据 RegisterWeightKey（为 0 时不写入
+	RegisterWeight int
+	// 健康检查的方式 CheckTTL, CheckGRPC
+	RegisterCheck string
+	// TTL 检查的超时时间（每隔一半的时间上报一次），或者 grpc 检查的间隔
+	RegisterCheckInterval time.Duration
+	// 健康检查失败多久之后由 consul 注销节点（consul 要求最少 1 分钟
+	RegisterDeregisterAfter time.Duration
 }
 
+var (
+	DefaultServerParm = Parm{
+		ListenAddr: ":14222",
+
+		GracefulStop:        true,
+		GracefulStopTimeout: time.Second * 5,
+
+		RegisterCheck:           CheckTTL,
+		RegisterCheckInterval:   time.Second * 10,
+		RegisterDeregisterAfter: time.Minute,
+	}
+)
+
 // Option config wraps
 type Option func(*Parm)
 
@@ -28,12 +79,125 @@ func WithListen(address string) Option {
 	}
 }
 
+// WithGracefulStop 优雅退出（默认开启
 func WithGracefulStop() Option {
 	return func(c *Parm) {
 		c.GracefulStop = true
 	}
 }
 
+// WithoutGracefulStop 关闭时直接断开处理中的请求
+func WithoutGracefulStop() Option {
+	return func(c *Parm) {
+		c.GracefulStop = false
+	}
+}
+
+// WithGracefulStopTimeout 优雅退出，停止接收新的请求并最多等待 timeout 时间让处理中的请求完成
+func WithGracefulStopTimeout(timeout time.Duration) Option {
+	return func(c *Parm) {
+		c.GracefulStop = true
+		c.GracefulStopTimeout = timeout
+	}
+}
+
+// WithTLS 启用 tls
+func WithTLS(certFile, keyFile string) Option {
+	return func(c *Parm) {
+		c.TLSCertFile = certFile
+		c.TLSKeyFile = keyFile
+	}
+}
+
+// WithClientCA 使用 caFile 验证客户端的证书（mTLS，需要同时设置 WithTLS
+func WithClientCA(caFile string) Option {
+	return func(c *Parm) {
+		c.TLSClientCAFile = caFile
+	}
+}
+
+// WithTLSReload 每隔 interval 检查一次证书文件，文件变更后新的连接使用新的证书
+func WithTLSReload(interval time.Duration) Option {
+	return func(c *Parm) {
+		c.TLSReloadInterval = interval
+	}
+}
+
+// WithConcurrencyLimit 启用按方法的自适应并发限制（见 DefaultLimitPolicy），超过上限的请求返回 codes.ResourceExhausted
+func WithConcurrencyLimit(policy LimitPolicy) Option {
+	return func(c *Parm) {
+		c.LimitPolicy = policy
+	}
+}
+
+// WithLimitListener 监听被并发限制拒绝的请求
+func WithLimitListener(listener LimitListener) Option {
+	return func(c *Parm) {
+		c.LimitListener = listener
+	}
+}
+
+// WithHealth 注册 grpc.health.v1.Health 服务，状态跟随 server 的生命周期（handler 中已经注册了自己的 health 服务时不要使用
+func WithHealth() Option {
+	return func(c *Parm) {
+		c.Health = true
+	}
+}
+
+// WithReflection 注册 grpc 反射服务，grpcurl 等工具可以直接获取服务列表和方法
+func WithReflection() Option {
+	return func(c *Parm) {
+		c.Reflection = true
+	}
+}
+
+// WithRegister 启动时将 server 注册到 consul（reg 通常为 *bconsul.Client），退出时注销
+//
+//	tags 注册时附带的标签，RegisterTag 总是会被添加
+func WithRegister(reg Registry, tags ...string) Option {
+	return func(c *Parm) {
+		c.Registry = reg
+		c.RegisterTags = append(c.RegisterTags, tags...)
+	}
+}
+
+// WithRegisterAddress 注册到 consul 的地址（如容器外可以访问到的地址
+func WithRegisterAddress(address string) Option {
+	return func(c *Parm) {
+		c.RegisterAddress = address
+	}
+}
+
+// WithRegisterWeight 注册到 consul 的节点权重
+func WithRegisterWeight(weight int) Option {
+	return func(c *Parm) {
+		c.RegisterWeight = weight
+	}
+}
+
+// WithRegisterTTL 使用 TTL 检查，server 每隔 ttl/2 向 consul 上报一次存活状态
+func WithRegisterTTL(ttl time.Duration) Option {
+	return func(c *Parm) {
+		c.RegisterCheck = CheckTTL
+		c.RegisterCheckInterval = ttl
+	}
+}
+
+// WithRegisterGRPCCheck 使用 grpc 检查，server 会注册 grpc.health.v1.Health 服务，consul 每隔 interval 检查一次
+func WithRegisterGRPCCheck(interval time.Duration) Option {
+	return func(c *Parm) {
+		c.RegisterCheck = CheckGRPC
+		c.RegisterCheckInterval = interval
+	}
+}
+
+// WithRegisterDeregisterAfter 健康检查失败 after 时间后由 consul 注销节点
+func WithRegisterDeregisterAfter(after time.Duration) Option {
+	return func(c *Parm) {
+		c.RegisterDeregisterAfter = after
+	}
+}
+
 func AppendUnaryInterceptors(interceptor grpc.UnaryServerInterceptor) Option {
 	return func(c *Parm) {
 		c.UnaryInterceptors = append(c.UnaryInterceptors, interceptor)
@@ -0,0 +1,223 @@
+package grpcserver
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/pojol/braid-go/components/internal/utils"
+)
+
+const (
+	// RegisterTag 注册到 consul 时默认附带的标签（discoverconsul 只会发现带有这个标签的服务
+	RegisterTag = "braid"
+
+	// CheckTTL 由 server 定时向 consul 上报存活状态
+	CheckTTL = "ttl"
+	// CheckGRPC 由 consul 调用 server 的 grpc.health.v1.Health 服务检查存活状态
+	CheckGRPC = "grpc"
+
+	// RegisterWeightKey 节点权重在 consul 元数据中的键（discoverconsul 从这个键获取节点的初始权重
+	RegisterWeightKey = "weight"
+)
+
+// Registry 服务注册中心（*bconsul.Client
+type Registry interface {
+	ServiceRegister(parm consul.AgentServiceRegistration) error
+	ServiceDeregister(id string) error
+	CheckUpdateTTL(checkID, output, status string) error
+}
+
+// registrar 将 server 注册到 consul，并维持健康检查的状态
+type registrar struct {
+	s   *grpcServer
+	reg Registry
+
+	id      string
+	checkID string
+
+	registered int32
+
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func newRegistrar(s *grpcServer) *registrar {
+	return &registrar{
+		s:       s,
+		reg:     s.parm.Registry,
+		id:      s.info.ID,
+		checkID: "service:" + s.info.ID,
+		done:    make(chan struct{}),
+	}
+}
+
+// address 注册的地址，没有设置时使用本机 ip 和侦听的端口
+func (r *registrar) address() (string, int, error) {
+
+	addr := r.s.parm.RegisterAddress
+	if addr == "" {
+		addr = r.s.parm.ListenAddr
+		if r.s.listen != nil {
+			addr = r.s.listen.Addr().String()
+		}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host, err = utils.GetLocalIP()
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return host, p, nil
+}
+
+func (r *registrar) registration() (consul.AgentServiceRegistration, error) {
+
+	host, port, err := r.address()
+	if err != nil {
+		return consul.AgentServiceRegistration{}, fmt.Errorf("register address err %w", err)
+	}
+
+	tags := []string{RegisterTag}
+	for _, tag := range r.s.parm.RegisterTags {
+		if !utils.ContainsInSlice(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	check := &consul.AgentServiceCheck{
+		CheckID:                        r.checkID,
+		DeregisterCriticalServiceAfter: r.s.parm.RegisterDeregisterAfter.String(),
+	}
+
+	if r.s.parm.RegisterCheck == CheckGRPC {
+		check.GRPC = net.JoinHostPort(host, strconv.Itoa(port))
+		check.GRPCUseTLS = r.s.tls != nil
+		check.TLSSkipVerify = r.s.tls != nil
+		check.Interval = r.s.parm.RegisterCheckInterval.String()
+	} else {
+		check.TTL = r.s.parm.RegisterCheckInterval.String()
+	}
+
+	parm := consul.AgentServiceRegistration{
+		ID:      r.id,
+		Name:    r.s.info.Name,
+		Tags:    tags,
+		Address: host,
+		Port:    port,
+		Check:   check,
+	}
+
+	if r.s.parm.RegisterWeight > 0 {
+		parm.Meta = map[string]string{RegisterWeightKey: strconv.Itoa(r.s.parm.RegisterWeight)}
+	}
+
+	return parm, nil
+}
+
+func (r *registrar) register() error {
+
+	parm, err := r.registration()
+	if err != nil {
+		return err
+	}
+
+	err = r.reg.ServiceRegister(parm)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&r.registered, 1)
+	r.s.log.Infof("[braid.server] register %v id %v addr %v:%v tags %v", parm.Name, parm.ID, parm.Address, parm.Port, parm.Tags)
+
+	return r.pass()
+}
+
+// pass 上报存活状态（grpc 检查由 consul 主动发起
+func (r *registrar) pass() error {
+	if r.s.parm.RegisterCheck == CheckGRPC {
+		return nil
+	}
+
+	return r.reg.CheckUpdateTTL(r.checkID, "serving", consul.HealthPassing)
+}
+
+// Run 注册到 consul，之后每隔 TTL 的一半上报一次存活状态；注册失败 or 检查丢失时重新注册
+func (r *registrar) Run() {
+
+	if err := r.register(); err != nil {
+		r.s.log.Warnf("[braid.server] register err %s", err.Error())
+	}
+
+	interval := r.s.parm.RegisterCheckInterval / 2
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				var err error
+				if atomic.LoadInt32(&r.registered) == 0 {
+					err = r.register()
+				} else if err = r.pass(); err != nil {
+					// consul 重启 or 检查超时被注销，下次重新注册
+					atomic.StoreInt32(&r.registered, 0)
+				}
+
+				if err != nil {
+					r.s.log.Warnf("[braid.server] register keepalive err %s", err.Error())
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Registered 是否已经注册到了 consul
+func (r *registrar) Registered() bool {
+	return atomic.LoadInt32(&r.registered) == 1
+}
+
+// Close 停止保活并从 consul 中注销
+func (r *registrar) Close() {
+	r.once.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+
+		if atomic.SwapInt32(&r.registered, 0) == 0 {
+			return
+		}
+
+		if err := r.reg.ServiceDeregister(r.id); err != nil {
+			r.s.log.Warnf("[braid.server] deregister %v err %s", r.id, err.Error())
+		} else {
+			r.s.log.Infof("[braid.server] deregister %v", r.id)
+		}
+	})
+}
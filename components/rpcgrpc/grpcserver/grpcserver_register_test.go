@@ -0,0 +1,155 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/pojol/braid-go/components/depends/blog"
+	"github.com/pojol/braid-go/components/rpcgrpc/proto"
+	"github.com/pojol/braid-go/module/meta"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// mockRegistry 记录注册信息的注册中心，failTTL 不为 0 时下一次 TTL 上报失败
+type mockRegistry struct {
+	services map[string]consul.AgentServiceRegistration
+	ttl      map[string]int
+	failTTL  int
+
+	registers int
+
+	sync.Mutex
+}
+
+func newMockRegistry() *mockRegistry {
+	return &mockRegistry{
+		services: make(map[string]consul.AgentServiceRegistration),
+		ttl:      make(map[string]int),
+	}
+}
+
+func (m *mockRegistry) ServiceRegister(parm consul.AgentServiceRegistration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.registers++
+	m.services[parm.ID] = parm
+	return nil
+}
+
+func (m *mockRegistry) ServiceDeregister(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.services, id)
+	return nil
+}
+
+func (m *mockRegistry) CheckUpdateTTL(checkID, output, status string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.failTTL > 0 {
+		m.failTTL--
+		return errors.New("check not found")
+	}
+
+	m.ttl[checkID]++
+	return nil
+}
+
+func (m *mockRegistry) get(id string) (consul.AgentServiceRegistration, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	parm, ok := m.services[id]
+	return parm, ok
+}
+
+func TestRegisterTTL(t *testing.T) {
+
+	reg := newMockRegistry()
+
+	s := BuildWithOption(
+		meta.ServiceInfo{Name: "register_ttl", ID: "register_ttl_1"},
+		blog.BuildWithOption(),
+		WithListen(":14114"),
+		WithRegister(reg, "game"),
+		WithRegisterAddress("10.0.0.1:14114"),
+		WithRegisterWeight(100),
+		WithRegisterTTL(time.Millisecond*40),
+		RegisterHandler(func(srv *grpc.Server) {
+			proto.RegisterListenServer(srv, &rpcServer{})
+		}),
+	)
+
+	assert.Nil(t, s.Init())
+	s.Run()
+
+	parm, ok := reg.get("register_ttl_1")
+	assert.True(t, ok)
+	assert.Equal(t, parm.Name, "register_ttl")
+	assert.Equal(t, parm.Tags, []string{RegisterTag, "game"})
+	assert.Equal(t, parm.Address, "10.0.0.1")
+	assert.Equal(t, parm.Port, 14114)
+	assert.Equal(t, parm.Meta[RegisterWeightKey], "100")
+	assert.Equal(t, parm.Check.TTL, "40ms")
+	assert.Equal(t, parm.Check.DeregisterCriticalServiceAfter, "1m0s")
+
+	// 上报失败后重新注册
+	reg.Lock()
+	reg.failTTL = 1
+	reg.Unlock()
+
+	time.Sleep(time.Millisecond * 100)
+
+	reg.Lock()
+	assert.Equal(t, reg.registers, 2)
+	assert.True(t, reg.ttl["service:register_ttl_1"] >= 2)
+	reg.Unlock()
+
+	s.Close()
+	_, ok = reg.get("register_ttl_1")
+	assert.False(t, ok)
+}
+
+func TestRegisterGRPCCheck(t *testing.T) {
+
+	reg := newMockRegistry()
+
+	s := BuildWithOption(
+		meta.ServiceInfo{Name: "register_grpc", ID: "register_grpc_1"},
+		blog.BuildWithOption(),
+		WithListen("127.0.0.1:14115"),
+		WithRegister(reg),
+		WithRegisterGRPCCheck(time.Second),
+		RegisterHandler(func(srv *grpc.Server) {
+			proto.RegisterListenServer(srv, &rpcServer{})
+		}),
+	)
+
+	assert.Nil(t, s.Init())
+	s.Run()
+	defer s.Close()
+
+	parm, ok := reg.get("register_grpc_1")
+	assert.True(t, ok)
+	assert.Equal(t, parm.Tags, []string{RegisterTag})
+	assert.Equal(t, parm.Check.GRPC, "127.0.0.1:14115")
+	assert.Equal(t, parm.Check.Interval, "1s")
+	assert.Nil(t, parm.Meta)
+
+	conn, err := grpc.Dial("127.0.0.1:14115", grpc.WithInsecure())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	res, err := healthpb.NewHealthClient(conn).Check(context.TODO(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, res.Status, healthpb.HealthCheckResponse_SERVING)
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/pojol/braid-go/module/meta"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
 type rpcServer struct {
@@ -24,6 +27,8 @@ func (rs *rpcServer) Routing(ctx context.Context, req *proto.RouteReq) (*proto.R
 
 	if req.Service == "test" {
 		err = nil
+	} else if req.Service == "slow" {
+		time.Sleep(time.Millisecond * 200)
 	} else {
 		err = errors.New("err")
 	}
@@ -88,3 +93,157 @@ func TestOpts(t *testing.T) {
 	//assert.Equal(t, cfg.isTracing, true)
 
 }
+
+func TestGracefulStop(t *testing.T) {
+	log := blog.BuildWithOption()
+
+	newServer := func(addr string, timeout time.Duration) *grpcServer {
+		s := BuildWithOption(
+			meta.ServiceInfo{
+				Name: "servergrpctest",
+				ID:   uuid.New().String(),
+			},
+			log,
+			WithListen(addr),
+			WithGracefulStopTimeout(timeout),
+			RegisterHandler(func(srv *grpc.Server) {
+				proto.RegisterListenServer(srv, &rpcServer{})
+			}),
+		).(*grpcServer)
+
+		assert.Equal(t, s.Init(), nil)
+		assert.False(t, s.Health(context.TODO()).Ready)
+		s.Run()
+		assert.True(t, s.Health(context.TODO()).Ready)
+		return s
+	}
+
+	slowCall := func(addr string) chan error {
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		assert.Equal(t, err, nil)
+
+		errch := make(chan error, 1)
+		go func() {
+			errch <- conn.Invoke(context.Background(), "/proto.listen/routing", &proto.RouteReq{
+				Service: "slow",
+			}, new(proto.RouteRes))
+			conn.Close()
+		}()
+
+		time.Sleep(time.Millisecond * 50)
+		return errch
+	}
+
+	// 处理中的请求在超时时间内完成
+	s := newServer(":14112", time.Second)
+	errch := slowCall(":14112")
+	s.Close()
+	assert.Equal(t, <-errch, nil)
+	assert.False(t, s.Health(context.TODO()).Ready)
+
+	// 超时后强制关闭
+	s = newServer(":14113", time.Millisecond*20)
+	errch = slowCall(":14113")
+	begin := time.Now()
+	s.Close()
+	assert.Less(t, time.Since(begin), time.Millisecond*150)
+	assert.NotEqual(t, <-errch, nil)
+}
+
+func TestHealthService(t *testing.T) {
+
+	s := BuildWithOption(
+		meta.ServiceInfo{
+			Name: "servergrpctest",
+			ID:   uuid.New().String(),
+		},
+		blog.BuildWithOption(),
+		WithListen(":14116"),
+		WithGracefulStop(),
+		WithHealth(),
+		WithReflection(),
+		RegisterHandler(func(srv *grpc.Server) {
+			proto.RegisterListenServer(srv, &rpcServer{})
+		}),
+	).(*grpcServer)
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		res, err := s.health.Check(context.TODO(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		return res.Status
+	}
+
+	// 初始化期间
+	assert.Equal(t, s.Init(), nil)
+	assert.Equal(t, check(""), healthpb.HealthCheckResponse_NOT_SERVING)
+	assert.Equal(t, check("proto.listen"), healthpb.HealthCheckResponse_NOT_SERVING)
+
+	s.Run()
+
+	conn, err := grpc.Dial(":14116", grpc.WithInsecure())
+	assert.Equal(t, err, nil)
+	defer conn.Close()
+
+	for _, service := range []string{"", "proto.listen"} {
+		res, err := healthpb.NewHealthClient(conn).Check(context.TODO(), &healthpb.HealthCheckRequest{Service: service})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, res.Status, healthpb.HealthCheckResponse_SERVING, service)
+	}
+
+	// 反射服务可以获取到用户注册的服务
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.TODO())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	}), nil)
+	res, err := stream.Recv()
+	assert.Equal(t, err, nil)
+	services := []string{}
+	for _, svc := range res.GetListServicesResponse().Service {
+		services = append(services, svc.Name)
+	}
+	assert.Contains(t, services, "proto.listen")
+	assert.Contains(t, services, healthpb.Health_ServiceDesc.ServiceName)
+	stream.CloseSend()
+
+	// 排空请求期间
+	go func() {
+		conn.Invoke(context.Background(), "/proto.listen/routing", &proto.RouteReq{Service: "slow"}, new(proto.RouteRes))
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	assert.Equal(t, check(""), healthpb.HealthCheckResponse_NOT_SERVING)
+	assert.Equal(t, check("proto.listen"), healthpb.HealthCheckResponse_NOT_SERVING)
+	<-closed
+}
+
+func TestHandlerHealthService(t *testing.T) {
+
+	// 默认不注册 health 服务，handler 可以注册自己的 health 服务
+	hs := health.NewServer()
+	s := BuildWithOption(
+		meta.ServiceInfo{
+			Name: "servergrpctest",
+			ID:   uuid.New().String(),
+		},
+		blog.BuildWithOption(),
+		WithListen(":14118"),
+		RegisterHandler(func(srv *grpc.Server) {
+			healthpb.RegisterHealthServer(srv, hs)
+		}),
+	).(*grpcServer)
+
+	assert.Equal(t, s.Init(), nil)
+	assert.Nil(t, s.health)
+	s.Run()
+	s.Close()
+}
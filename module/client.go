@@ -3,8 +3,21 @@ package module
 
 import (
 	"context"
+
+	"github.com/pojol/braid-go/module/meta"
+	"google.golang.org/grpc"
 )
 
+// InvokeResult 广播调用（InvokeAll）中单个节点的调用结果
+type InvokeResult struct {
+	Node meta.Node
+
+	// Reply 节点返回的参数（由 InvokeAll 的 newReply 创建
+	Reply interface{}
+
+	Err error
+}
+
 // IClient rpc-client interface
 type IClient interface {
 	Init() error
@@ -33,4 +46,22 @@ type IClient interface {
 		ctx context.Context, target, methon, token string,
 		args, reply interface{},
 		opts ...interface{}) error
+
+	// NewStream 创建一个流式调用（客户端流，服务端流，双向流
+	//
+	// 目标节点的选取方式和 Invoke 相同，desc 描述流的类型
+	NewStream(
+		ctx context.Context, target, methon, token string,
+		desc *grpc.StreamDesc,
+		opts ...interface{}) (grpc.ClientStream, error)
+
+	// InvokeAll 并发调用 target 服务当前的所有节点（广播，不经过 linkcache 和负载均衡
+	//
+	// newReply 为每个节点创建一个返回参数
+	//
+	// 返回每个节点的调用结果，成功的节点数没有达到 quorum 时返回错误
+	InvokeAll(
+		ctx context.Context, target, methon string,
+		args interface{}, newReply func() interface{},
+		opts ...interface{}) ([]InvokeResult, error)
 }
@@ -0,0 +1,21 @@
+// 健康检查 模块接口文件
+package module
+
+import "context"
+
+// HealthStatus 模块的健康状态
+type HealthStatus struct {
+	// 存活，为 false 时表示模块已经无法自行恢复（通常需要重启进程
+	Live bool `json:"live"`
+
+	// 就绪，为 false 时表示模块暂时无法提供服务（如依赖的 redis 无法访问，服务发现还没有完成同步
+	Ready bool `json:"ready"`
+
+	// 状态描述
+	Message string `json:"message,omitempty"`
+}
+
+// IHealth 可选的健康检查接口，模块实现后会被 director 汇总到 liveness / readiness 报告中
+type IHealth interface {
+	Health(ctx context.Context) HealthStatus
+}
@@ -10,4 +10,7 @@ const (
 	ModuleClient   = "barid.module.client"   // rpc客户端
 	ModuleServer   = "barid.module.server"   // rpc服务端
 	ModuleTracer   = "braid.module.tracer"   // 链路追踪
+	ModuleHealth   = "braid.module.health"   // 健康检查
+	ModuleEvent    = "braid.module.event"    // 事件回调
+	ModuleDepends  = "braid.module.depends"  // 依赖的外部服务客户端（redis ...
 )
@@ -20,13 +20,16 @@ type Node struct {
 	Address string
 	Port    int
 
+	// Weight 节点权重（平滑加权轮询使用，由服务发现从注册的元数据中获取
+	Weight int
+
 	Metadata map[string]interface{}
 }
 
 func (n *Node) GetWidget() int {
-	return 0
+	return n.Weight
 }
 
 func (n *Node) SetWidget(widget int) {
-
+	n.Weight = widget
 }
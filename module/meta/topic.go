@@ -20,6 +20,8 @@ const (
 	TopicLinkcacheLinkNumber = "braid.topic.linkcache.service_link_number"
 	// 链路缓存 - 有用户断开（下线，不再需要持有链路信息
 	TopicLinkcacheUnlink = "braid.topic.linkcache.unlink"
+	// 链路缓存 - 用户的链路信息已经被移除（Unlink，目标节点下线，退出时释放 token），消息内容为 token
+	TopicLinkcacheUnlinked = "braid.topic.linkcache.unlinked"
 
 	// --------------------------------------------------
 